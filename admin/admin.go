@@ -5,15 +5,19 @@ package admin
 
 import (
 	"bookweb/config"
+	"bookweb/controller"
 	"bookweb/dao"
 	"bookweb/plugin"
+	"bookweb/service"
 	"bookweb/utils"
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"net/http"
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 // 模板路径
@@ -87,7 +91,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, adminPath, http.StatusFound)
 }
 
-// ClearCache 清理 Redis 缓存
+// ClearCache 按范围 (all/page/data/search) 清理进程内缓存及本站 Redis 缓存
 func ClearCache(w http.ResponseWriter, r *http.Request) {
 	_, ok := IsAdminLoggedIn(r)
 	if !ok {
@@ -95,12 +99,24 @@ func ClearCache(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := utils.CacheFlush(); err != nil {
+	scope := r.FormValue("scope")
+	if scope == "" {
+		scope = dao.CacheScopeAll
+	}
+	deleted, err := dao.ClearCache(scope)
+	if err != nil {
 		jsonResponse(w, map[string]interface{}{"success": false, "message": "清理失败: " + err.Error()})
 		return
 	}
+	if scope == dao.CacheScopeAll || scope == dao.CacheScopePage {
+		utils.BumpETagVersion()
+	}
 
-	jsonResponse(w, map[string]interface{}{"success": true, "message": "Redis 缓存已清空"})
+	if !utils.IsRedisEnabled() {
+		jsonResponse(w, map[string]interface{}{"success": true, "message": "进程内缓存已清空"})
+		return
+	}
+	jsonResponse(w, map[string]interface{}{"success": true, "message": fmt.Sprintf("缓存已清理，共删除 %d 个 Redis 键", deleted)})
 }
 
 // ClearTemplates 清理模板缓存 (重新加载)
@@ -119,6 +135,22 @@ func ClearTemplates(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, map[string]interface{}{"success": true, "message": "模板缓存已重载"})
 }
 
+// StaticGenerate 后台触发静态页生成 (在后台执行，结果在系统设置 - 静态页中查看)
+func StaticGenerate(w http.ResponseWriter, r *http.Request) {
+	if controller.GetStaticReport().Running {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": "静态页正在生成中"})
+		return
+	}
+
+	force := r.FormValue("force") == "1"
+	go func() {
+		if _, err := controller.GenerateStaticPages(force); err != nil {
+			utils.LogError("Static", "Static generation failed: %v", err)
+		}
+	}()
+	jsonResponse(w, map[string]interface{}{"success": true, "message": "已开始生成，可稍后刷新页面查看进度"})
+}
+
 // Logout 后台注销
 func Logout(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie(AdminSessionCookieName)
@@ -146,6 +178,10 @@ func Dashboard(w http.ResponseWriter, r *http.Request) {
 	}
 	data := getAdminData(r, "dashboard", "仪表板")
 	data["Stats"] = stats // 追加额外数据
+	data["ContentCache"] = utils.GetContentCacheStats()
+	data["DataCache"] = utils.GetDataCacheStats()
+	data["VisitStats"] = dao.GetVisitStats()
+	data["VisitFilter"] = service.GetVisitFilterStats()
 	t.ExecuteTemplate(w, "layout", data)
 }
 
@@ -158,6 +194,12 @@ func Settings(w http.ResponseWriter, r *http.Request) {
 
 		if updateType == "basic" {
 			// 保存基本设置和存储设置
+			timezone := strings.TrimSpace(r.FormValue("timezone"))
+			if err := utils.SetSiteTimezone(timezone); err != nil {
+				jsonResponse(w, map[string]interface{}{"success": false, "message": "站点时区无效: " + err.Error()})
+				return
+			}
+			cfg.Site.Timezone = timezone
 			cfg.Site.SiteName = r.FormValue("sitename")
 			cfg.Site.Domain = r.FormValue("domain")
 			cfg.Site.MobileDomain = r.FormValue("mobile_domain")
@@ -173,14 +215,26 @@ func Settings(w http.ResponseWriter, r *http.Request) {
 			cfg.Site.SortCache = r.FormValue("sort_cache") == "on"
 			cfg.Site.TopCache = r.FormValue("top_cache") == "on"
 			cfg.Site.TopCache = r.FormValue("top_cache") == "on"
+			cfg.Site.SearchCache = r.FormValue("search_cache") == "on"
 			cfg.Site.ForceDomain = r.FormValue("force_domain") == "on"
 			cfg.Site.IdTransRule = r.FormValue("id_trans_rule")
 			cfg.Site.GzipEnabled = r.FormValue("gzip_enabled") == "on"
+			cfg.Site.DownloadEnabled = r.FormValue("download_enabled") == "on"
+			if limit, err := strconv.Atoi(r.FormValue("download_limit")); err == nil {
+				cfg.Site.DownloadLimit = limit
+			}
+			if size, err := strconv.Atoi(r.FormValue("vip_preview_size")); err == nil {
+				cfg.Site.VipPreviewSize = size
+			}
+			if votes, err := strconv.Atoi(r.FormValue("daily_votes")); err == nil {
+				cfg.Site.DailyVotes = votes
+			}
 
 			// 更新 ID 转换规则
 			utils.ParseIdTransRule(cfg.Site.IdTransRule)
 
 			cfg.Storage.Type = r.FormValue("storage_type")
+			cfg.Storage.Encoding = r.FormValue("storage_encoding")
 
 			// 保存本地存储配置
 			cfg.Storage.Local.Path = r.FormValue("storage_path")
@@ -191,6 +245,8 @@ func Settings(w http.ResponseWriter, r *http.Request) {
 			cfg.Storage.Oss.SecretKey = r.FormValue("oss_secret_key")
 			cfg.Storage.Oss.Bucket = r.FormValue("oss_bucket")
 			cfg.Storage.Oss.Domain = r.FormValue("oss_domain")
+			cfg.Storage.Oss.Region = r.FormValue("oss_region")
+			cfg.Storage.Oss.PathStyle = r.FormValue("oss_path_style") == "on"
 		} else if updateType == "db" {
 			// 保存数据库配置
 			cfg.Db.Driver = r.FormValue("db_driver")
@@ -210,11 +266,27 @@ func Settings(w http.ResponseWriter, r *http.Request) {
 			}
 		} else if updateType == "redis" {
 			// 保存 Redis 配置
-			cfg.Redis.Enabled = r.FormValue("redis_enabled") == "on"
-			cfg.Redis.Host = r.FormValue("redis_host")
-			cfg.Redis.Port, _ = strconv.Atoi(r.FormValue("redis_port"))
-			cfg.Redis.Password = r.FormValue("redis_password")
-			cfg.Redis.DB, _ = strconv.Atoi(r.FormValue("redis_db"))
+			cfg.Redis = parseRedisForm(r)
+			cfg.Cache.ContentCacheSize, _ = strconv.Atoi(r.FormValue("content_cache_size"))
+			cfg.Cache.DataCacheSize, _ = strconv.Atoi(r.FormValue("data_cache_size"))
+			cfg.Cache.StaleTTL, _ = strconv.Atoi(r.FormValue("stale_ttl"))
+			cfg.Cache.TTLJitter, _ = strconv.Atoi(r.FormValue("ttl_jitter"))
+			cfg.Cache.PageTTL = config.ParsePageTTL(r.FormValue("page_ttl"))
+		} else if updateType == "static" {
+			// 保存静态页配置
+			cfg.Static.Enabled = r.FormValue("static_enabled") == "on"
+			cfg.Static.Dir = strings.TrimSpace(r.FormValue("static_dir"))
+			cfg.Static.Interval, _ = strconv.Atoi(r.FormValue("static_interval"))
+			cfg.Static.Workers, _ = strconv.Atoi(r.FormValue("static_workers"))
+		} else if updateType == "visit" {
+			// 保存点击量回写配置 (回写间隔重启后生效)
+			cfg.Visit.FlushInterval, _ = strconv.Atoi(r.FormValue("visit_flush_interval"))
+			cfg.Visit.BatchSize, _ = strconv.Atoi(r.FormValue("visit_batch_size"))
+			cfg.Visit.RedisBuffer = r.FormValue("visit_redis_buffer") == "on"
+			cfg.Visit.Mode = r.FormValue("visit_mode")
+			cfg.Visit.DedupWindow, _ = strconv.Atoi(r.FormValue("visit_dedup_window"))
+			cfg.Visit.DedupRedis = r.FormValue("visit_dedup_redis") == "on"
+			cfg.Visit.IPHourlyLimit, _ = strconv.Atoi(r.FormValue("visit_ip_hourly_limit"))
 		} else if updateType == "log" {
 			// 保存日志配置
 			cfg.Log.Level = r.FormValue("log_level")
@@ -255,6 +327,7 @@ func Settings(w http.ResponseWriter, r *http.Request) {
 	}
 	data := getAdminData(r, "settings", "系统设置")
 	data["Config"] = cfg
+	data["StaticReport"] = controller.GetStaticReport()
 	t.ExecuteTemplate(w, "layout", data)
 }
 
@@ -298,11 +371,18 @@ func ArticleEdit(w http.ResponseWriter, r *http.Request) {
 		fullFlag, _ := strconv.Atoi(r.FormValue("fullflag"))
 		intro := r.FormValue("intro")
 
+		// 记录原分类，修改分类时新旧分类列表均需清理
+		oldSortID := sortID
+		if old, err := dao.GetArticleByIDAdmin(id); err == nil {
+			oldSortID = old.SortID
+		}
+
 		err := dao.UpdateArticleAdmin(id, name, author, sortID, fullFlag, intro)
 		if err != nil {
 			jsonResponse(w, map[string]interface{}{"success": false, "message": err.Error()})
 			return
 		}
+		dao.InvalidateArticleDependents(id, oldSortID, sortID)
 		jsonResponse(w, map[string]interface{}{"success": true, "message": "保存成功"})
 		return
 	}
@@ -329,14 +409,138 @@ func ArticleEdit(w http.ResponseWriter, r *http.Request) {
 // ArticleDelete 删除小说
 func ArticleDelete(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(r.FormValue("id"))
+	article, _ := dao.GetArticleByIDAdmin(id)
 	err := dao.DeleteArticleAdmin(id)
 	if err != nil {
 		jsonResponse(w, map[string]interface{}{"success": false, "message": err.Error()})
 		return
 	}
+	if article != nil {
+		dao.InvalidateArticleDependents(id, article.SortID)
+	} else {
+		dao.InvalidateArticleDependents(id)
+	}
 	jsonResponse(w, map[string]interface{}{"success": true, "message": "删除成功"})
 }
 
+// ArticleDisplay 显示 / 隐藏小说
+func ArticleDisplay(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	display, _ := strconv.Atoi(r.FormValue("display"))
+	if display != 0 {
+		display = 1
+	}
+	if err := dao.SetArticleDisplay(id, display); err != nil {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": err.Error()})
+		return
+	}
+	// 隐藏 / 显示影响分类列表、首页及排行
+	if article, err := dao.GetArticleByIDAdmin(id); err == nil {
+		dao.InvalidateArticleDependents(id, article.SortID)
+	} else {
+		dao.InvalidateArticleDependents(id)
+	}
+	jsonResponse(w, map[string]interface{}{"success": true, "message": "操作成功"})
+}
+
+// ArticleChapters 章节管理页面
+func ArticleChapters(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	article, err := dao.GetArticleByIDAdmin(id)
+	if err != nil {
+		http.Error(w, "小说不存在", http.StatusNotFound)
+		return
+	}
+	chapters, err := dao.GetChapterListAdmin(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	t, err := parseTpl("layout.html", "chapters.html")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := getAdminData(r, "articles", "章节管理")
+	data["Article"] = article
+	data["Chapters"] = chapters
+	t.ExecuteTemplate(w, "layout", data)
+}
+
+// ChapterDisplay 显示 / 隐藏章节，并重新统计小说的章节数及最新章节
+func ChapterDisplay(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	display, _ := strconv.Atoi(r.FormValue("display"))
+	if display != 0 {
+		display = 1
+	}
+	chapter, err := dao.GetChapterByIDAdmin(id)
+	if err != nil {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": "章节不存在"})
+		return
+	}
+	if err := dao.SetChapterDisplay(id, display); err != nil {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": err.Error()})
+		return
+	}
+	if err := dao.RecountArticleStats(chapter.ArticleID); err != nil {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": "更新小说统计失败: " + err.Error()})
+		return
+	}
+	dao.InvalidateArticleVisibility(chapter.ArticleID)
+	controller.RegenerateArticleStatic(chapter.ArticleID)
+	jsonResponse(w, map[string]interface{}{"success": true, "message": "操作成功"})
+}
+
+// ChapterEdit 编辑章节
+func ChapterEdit(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		id, _ := strconv.Atoi(r.FormValue("id"))
+		chapter, err := dao.GetChapterByIDAdmin(id)
+		if err != nil {
+			jsonResponse(w, map[string]interface{}{"success": false, "message": "章节不存在"})
+			return
+		}
+		name := r.FormValue("chaptername")
+		content := r.FormValue("content")
+
+		if err := utils.SaveChapterFileText(chapter.ArticleID, chapter.ChapterID, chapter.ChapterOrder, content); err != nil {
+			jsonResponse(w, map[string]interface{}{"success": false, "message": "保存内容失败: " + err.Error()})
+			return
+		}
+		if err := dao.UpdateChapterAdmin(id, name, len([]rune(content))); err != nil {
+			jsonResponse(w, map[string]interface{}{"success": false, "message": err.Error()})
+			return
+		}
+
+		// 使章节内容、目录及信息页 / 目录页缓存失效
+		dao.InvalidateChapterCache(chapter.ArticleID, chapter.ChapterID)
+		dao.InvalidateArticleVisibility(chapter.ArticleID)
+		service.RemoveBookEpub(chapter.ArticleID)
+		jsonResponse(w, map[string]interface{}{"success": true, "message": "保存成功"})
+		return
+	}
+
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	chapter, err := dao.GetChapterByIDAdmin(id)
+	if err != nil {
+		http.Error(w, "章节不存在", http.StatusNotFound)
+		return
+	}
+	content, _ := utils.GetChapterFileText(chapter.ArticleID, chapter.ChapterID, chapter.ChapterOrder)
+
+	t, err := parseTpl("layout.html", "chapter_edit.html")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := getAdminData(r, "articles", "编辑章节")
+	data["Chapter"] = chapter
+	data["Content"] = content
+	t.ExecuteTemplate(w, "layout", data)
+}
+
 // Users 用户管理页面
 func Users(w http.ResponseWriter, r *http.Request) {
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
@@ -365,6 +569,66 @@ func Users(w http.ResponseWriter, r *http.Request) {
 	t.ExecuteTemplate(w, "layout", data)
 }
 
+// Wallet 钱包流水 (?userid= 按用户筛选)
+func Wallet(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	userID, _ := strconv.Atoi(r.URL.Query().Get("userid"))
+
+	logs, total, err := dao.GetWalletLogs(userID, page, 20)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	totalPage := (total + 19) / 20
+
+	t, err := parseTpl("layout.html", "wallet.html")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := getAdminData(r, "wallet", "钱包流水")
+	data["Logs"] = logs
+	data["Page"] = page
+	data["TotalPage"] = totalPage
+	data["Total"] = total
+	data["UserID"] = userID
+	if userID > 0 {
+		if user, err := dao.GetUserByIDAdmin(userID); err == nil {
+			data["User"] = user
+			data["Balance"], _ = dao.GetWalletBalance(userID)
+		}
+	}
+	t.ExecuteTemplate(w, "layout", data)
+}
+
+// WalletTopUp 手动充值 (金额为负数时扣减)
+func WalletTopUp(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.FormValue("userid"))
+	amount, _ := strconv.Atoi(r.FormValue("amount"))
+	remark := strings.TrimSpace(r.FormValue("remark"))
+	if amount == 0 {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": "请输入金额"})
+		return
+	}
+	if _, err := dao.GetUserByIDAdmin(userID); err != nil {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": "用户不存在"})
+		return
+	}
+	if remark == "" {
+		remark = "后台充值"
+	}
+
+	balance, err := dao.TopUpWallet(userID, amount, remark)
+	if err != nil {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": err.Error()})
+		return
+	}
+	jsonResponse(w, map[string]interface{}{"success": true, "message": fmt.Sprintf("操作成功，当前余额 %d", balance)})
+}
+
 // UserDelete 删除用户
 func UserDelete(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(r.FormValue("id"))
@@ -593,7 +857,7 @@ func Modules(w http.ResponseWriter, r *http.Request) {
 	sorts, _ := dao.GetAllSorts()
 
 	// 过滤路由：只显示允许自定义的路由
-	allowedKeys := []string{"book", "book_index", "book_index_page", "read", "sort", "top"}
+	allowedKeys := []string{"book", "book_index", "book_index_page", "book_download", "book_epub", "read", "sort", "top"}
 	displayRoutes := make(map[string]string)
 	for _, key := range allowedKeys {
 		if val, ok := routerCfg.Routes[key]; ok {
@@ -639,6 +903,170 @@ func Analytics(w http.ResponseWriter, r *http.Request) {
 	t.ExecuteTemplate(w, "layout", data)
 }
 
+// Filters 章节内容过滤规则管理
+func Filters(w http.ResponseWriter, r *http.Request) {
+	cfg := config.GetGlobalConfig()
+
+	if r.Method == "POST" {
+		var rules []config.FilterRule
+		if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+			jsonResponse(w, map[string]interface{}{"success": false, "message": "无效的数据格式"})
+			return
+		}
+		// 保存前校验规则，避免错误的正则导致过滤失效
+		if _, err := utils.CompileTextFilter(rules); err != nil {
+			jsonResponse(w, map[string]interface{}{"success": false, "message": err.Error()})
+			return
+		}
+
+		if err := config.UpdateFilterConfig("config/filter.conf", rules); err != nil {
+			jsonResponse(w, map[string]interface{}{"success": false, "message": err.Error()})
+			return
+		}
+		if err := utils.ReloadTextFilter(); err != nil {
+			jsonResponse(w, map[string]interface{}{"success": false, "message": err.Error()})
+			return
+		}
+		jsonResponse(w, map[string]interface{}{"success": true, "message": "过滤规则保存成功"})
+		return
+	}
+
+	t, err := parseTpl("layout.html", "filters.html")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rules := cfg.FilterRules
+	if rules == nil {
+		rules = []config.FilterRule{}
+	}
+	data := getAdminData(r, "filters", "内容过滤")
+	data["Rules"] = rules
+	t.ExecuteTemplate(w, "layout", data)
+}
+
+// FilterPreview 使用未保存的规则预览章节过滤效果
+func FilterPreview(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Rules     []config.FilterRule `json:"rules"`
+		ChapterID int                 `json:"chapterid"`
+		Text      string              `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": "无效的数据格式"})
+		return
+	}
+
+	filter, err := utils.CompileTextFilter(req.Rules)
+	if err != nil {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": err.Error()})
+		return
+	}
+
+	text := req.Text
+	if req.ChapterID > 0 {
+		chapter, err := dao.GetChapterByIDAdmin(req.ChapterID)
+		if err != nil {
+			jsonResponse(w, map[string]interface{}{"success": false, "message": "章节不存在"})
+			return
+		}
+		if text, err = utils.GetChapterFileText(chapter.ArticleID, chapter.ChapterID, chapter.ChapterOrder); err != nil {
+			jsonResponse(w, map[string]interface{}{"success": false, "message": "读取章节内容失败: " + err.Error()})
+			return
+		}
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	filtered := filter.Apply(text)
+	jsonResponse(w, map[string]interface{}{
+		"success":  true,
+		"original": text,
+		"filtered": filtered,
+		"html":     utils.FormatChapterHTML(filtered),
+	})
+}
+
+// maxIntegrityIssues 页面最多展示的问题条数
+const maxIntegrityIssues = 500
+
+// Integrity 存储校验页面 (?run=1 时执行校验，全站校验在后台执行，页面显示进度及最近一次结果)
+func Integrity(w http.ResponseWriter, r *http.Request) {
+	data := getAdminData(r, "integrity", "存储校验")
+	articleID, _ := strconv.Atoi(r.URL.Query().Get("article"))
+	data["ArticleID"] = articleID
+
+	run := r.URL.Query().Get("run") == "1"
+	if run && articleID == 0 {
+		service.StartIntegrityTask(service.IntegrityOptions{Tolerance: 0.1}, false, false)
+		http.Redirect(w, r, data["AdminPath"].(string)+"/integrity", http.StatusFound)
+		return
+	}
+
+	t, err := parseTpl("layout.html", "integrity.html")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var report *service.IntegrityReport
+	if run {
+		report, err = service.CheckStorageIntegrity(service.IntegrityOptions{ArticleID: articleID, Tolerance: 0.1})
+		if err != nil {
+			data["Error"] = err.Error()
+		}
+	} else if articleID == 0 {
+		task := service.GetIntegrityTask()
+		data["Task"] = task
+		if !task.Running {
+			report = task.Report
+		}
+	}
+	if report != nil {
+		issues := report.Issues
+		if len(issues) > maxIntegrityIssues {
+			issues = issues[:maxIntegrityIssues]
+		}
+		data["Report"] = report
+		data["Issues"] = issues
+	}
+	t.ExecuteTemplate(w, "layout", data)
+}
+
+// IntegrityFix 重新校验并修复 (隐藏异常章节 / 删除孤立文件)，全站修复在后台执行
+func IntegrityFix(w http.ResponseWriter, r *http.Request) {
+	articleID, _ := strconv.Atoi(r.FormValue("article"))
+	hide := r.FormValue("hide") == "1"
+	orphans := r.FormValue("orphans") == "1"
+	if !hide && !orphans {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": "请选择修复方式"})
+		return
+	}
+
+	if articleID == 0 {
+		if !service.StartIntegrityTask(service.IntegrityOptions{Tolerance: 0.1}, hide, orphans) {
+			jsonResponse(w, map[string]interface{}{"success": false, "message": "校验任务正在执行中"})
+			return
+		}
+		jsonResponse(w, map[string]interface{}{"success": true, "message": "已开始后台校验并修复，可稍后刷新页面查看进度"})
+		return
+	}
+
+	report, err := service.CheckStorageIntegrity(service.IntegrityOptions{ArticleID: articleID, Tolerance: 0.1})
+	if err != nil {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": "校验失败: " + err.Error()})
+		return
+	}
+	result, err := service.FixIntegrityIssues(report.Issues, hide, orphans)
+	if err != nil {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": "修复失败: " + err.Error()})
+		return
+	}
+	jsonResponse(w, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("修复完成: 隐藏章节 %d 个, 删除文件 %d 个", result.Hidden, result.Deleted),
+	})
+}
+
 // ModuleRoutesUpdate 更新路由配置
 func ModuleRoutesUpdate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -657,6 +1085,8 @@ func ModuleRoutesUpdate(w http.ResponseWriter, r *http.Request) {
 		"book":            true,
 		"book_index":      true,
 		"book_index_page": true,
+		"book_download":   true,
+		"book_epub":       true,
 		"read":            true,
 		"sort":            true,
 		"top":             true,
@@ -696,11 +1126,19 @@ func ModuleSortsUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var tags []string
 	for _, s := range sorts {
 		if err := dao.UpdateSort(s.SortID, s.Caption, s.ShortName, s.Weight); err != nil {
 			jsonResponse(w, map[string]interface{}{"success": false, "message": "更新分类 " + s.Caption + " 失败: " + err.Error()})
 			return
 		}
+		tags = append(tags, dao.SortTag(s.SortID))
+	}
+	dao.InvalidateSortsCache()
+	utils.InvalidateCacheTags(tags...)
+	// 分类名称出现在所有页面的导航中
+	if _, err := utils.ClearPageCache(); err != nil {
+		utils.LogWarn("Admin", "Clear page cache failed: %v", err)
 	}
 
 	jsonResponse(w, map[string]interface{}{"success": true, "message": "分类保存成功"})
@@ -856,33 +1294,47 @@ func SecurityPath(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, map[string]interface{}{"success": true, "message": "入口已修改，正在跳转...", "new_path": newPath})
 }
 
+// parseRedisForm 从设置表单 (redis_* 字段) 读取 Redis 配置
+func parseRedisForm(r *http.Request) config.RedisConfig {
+	var cfg config.RedisConfig
+	cfg.Enabled = r.FormValue("redis_enabled") == "on"
+	cfg.Host = r.FormValue("redis_host")
+	cfg.Port, _ = strconv.Atoi(r.FormValue("redis_port"))
+	cfg.Password = r.FormValue("redis_password")
+	cfg.DB, _ = strconv.Atoi(r.FormValue("redis_db"))
+	cfg.Prefix = strings.TrimSpace(r.FormValue("redis_prefix"))
+	cfg.Mode = r.FormValue("redis_mode")
+	cfg.MasterName = strings.TrimSpace(r.FormValue("redis_master_name"))
+	for _, addr := range strings.Split(r.FormValue("redis_addrs"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			cfg.Addrs = append(cfg.Addrs, addr)
+		}
+	}
+	cfg.SentinelPassword = r.FormValue("redis_sentinel_password")
+	cfg.DialTimeout, _ = strconv.Atoi(r.FormValue("redis_dial_timeout"))
+	cfg.ReadTimeout, _ = strconv.Atoi(r.FormValue("redis_read_timeout"))
+	cfg.WriteTimeout, _ = strconv.Atoi(r.FormValue("redis_write_timeout"))
+	cfg.PoolSize, _ = strconv.Atoi(r.FormValue("redis_pool_size"))
+	return cfg
+}
+
 // TestRedisConnection 测试 Redis 连接
+// 使用表单中的配置创建临时客户端并 PING，测试后关闭，不影响当前使用的连接
 func TestRedisConnection(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	host := r.FormValue("host")
-	portStr := r.FormValue("port")
-	password := r.FormValue("password")
-	dbStr := r.FormValue("db")
-
-	port, _ := strconv.Atoi(portStr)
-	db, _ := strconv.Atoi(dbStr)
-
-	// 创建测试配置
-	testCfg := &config.RedisConfig{
-		Enabled:  true,
-		Host:     host,
-		Port:     port,
-		Password: password,
-		DB:       db,
+	testCfg := parseRedisForm(r)
+	client, err := utils.NewRedisClient(&testCfg)
+	if err != nil {
+		jsonResponse(w, map[string]interface{}{"success": false, "message": "配置错误: " + err.Error()})
+		return
 	}
+	defer client.Close()
 
-	// 尝试连接
-	err := utils.InitRedis(testCfg)
-	if err != nil {
+	if err := client.Ping(r.Context()).Err(); err != nil {
 		jsonResponse(w, map[string]interface{}{"success": false, "message": "连接失败: " + err.Error()})
 		return
 	}
@@ -0,0 +1,118 @@
+// main.go (checkstore)
+// 存储校验工具
+// 比对章节表与存储后端的章节文件，输出 JSON / CSV 报告，可选隐藏异常章节或删除孤立文件
+package main
+
+import (
+	"bookweb/config"
+	"bookweb/service"
+	"bookweb/utils"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+func main() {
+	configPath := flag.String("config", "config/config.conf", "配置文件路径")
+	articleID := flag.Int("article", 0, "仅校验指定小说 ID (默认全站)")
+	format := flag.String("format", "json", "报告格式 (json/csv)")
+	out := flag.String("out", "", "报告输出文件 (默认标准输出)")
+	tolerance := flag.Float64("tolerance", 0.1, "字数允许误差比例")
+	fixHide := flag.Bool("fix-hide", false, "隐藏文件缺失或为空的章节")
+	fixOrphans := flag.Bool("fix-orphans", false, "删除无章节记录的孤立文件")
+	flag.Parse()
+
+	if *format != "json" && *format != "csv" {
+		fmt.Fprintln(os.Stderr, "Error: -format 仅支持 json 或 csv")
+		os.Exit(2)
+	}
+
+	appCfg, err := config.LoadAppConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 加载配置失败:", err)
+		os.Exit(1)
+	}
+	utils.InitDB(&appCfg.Db)
+	if err := utils.Db.Ping(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 数据库连接失败:", err)
+		os.Exit(1)
+	}
+
+	report, err := service.CheckStorageIntegrity(service.IntegrityOptions{
+		ArticleID: *articleID,
+		Tolerance: *tolerance,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 校验失败:", err)
+		os.Exit(1)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "csv" {
+		err = writeCSV(w, report.Issues)
+	} else {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 写入报告失败:", err)
+		os.Exit(1)
+	}
+
+	// 摘要输出到标准错误，避免混入报告
+	fmt.Fprintf(os.Stderr, "章节 %d, 文件 %d, 缺失 %d, 孤立 %d, 空文件 %d, 字数不符 %d\n",
+		report.Chapters, report.Files,
+		report.Counts[service.IssueMissing], report.Counts[service.IssueOrphaned],
+		report.Counts[service.IssueZeroByte], report.Counts[service.IssueSizeMismatch])
+
+	if !*fixHide && !*fixOrphans {
+		return
+	}
+	// 修复会修改章节显示状态，需同时清理站点进程共享的 Redis 缓存 (与 importer 一致)
+	if *fixHide && appCfg.Redis.Enabled {
+		if err := utils.InitRedis(&appCfg.Redis); err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: Redis 连接失败，站点缓存需等待过期后才会反映隐藏的章节:", err)
+		}
+	}
+	result, err := service.FixIntegrityIssues(report.Issues, *fixHide, *fixOrphans)
+	if result != nil {
+		fmt.Fprintf(os.Stderr, "修复完成: 隐藏章节 %d, 删除文件 %d\n", result.Hidden, result.Deleted)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 修复失败:", err)
+		os.Exit(1)
+	}
+}
+
+// writeCSV 以 CSV 格式输出问题列表
+func writeCSV(w io.Writer, issues []service.IntegrityIssue) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"type", "articleid", "chapterid", "chaptername", "path", "expected", "actual"})
+	for _, issue := range issues {
+		cw.Write([]string{
+			issue.Type,
+			strconv.Itoa(issue.ArticleID),
+			strconv.Itoa(issue.ChapterID),
+			issue.ChapterName,
+			issue.Path,
+			strconv.Itoa(issue.Expected),
+			strconv.Itoa(issue.Actual),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,80 @@
+// main.go (encstat)
+// 章节编码统计工具
+// 通过存储后端扫描章节文本，统计各编码 (UTF-8 / GBK / GB18030 / UTF-16 等) 的文件分布
+package main
+
+import (
+	"bookweb/config"
+	"bookweb/utils"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+func main() {
+	configPath := flag.String("config", "config/config.conf", "配置文件路径")
+	prefix := flag.String("prefix", "article/txt", "章节文本在存储中的路径前缀")
+	list := flag.String("list", "", "列出指定编码的文件 (如 utf-8、gbk、unknown)")
+	flag.Parse()
+
+	if _, err := config.LoadAppConfig(*configPath); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 加载配置失败:", err)
+		os.Exit(1)
+	}
+	storage, err := utils.GetStorage()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 初始化存储失败:", err)
+		os.Exit(1)
+	}
+	files, err := storage.List(*prefix)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	counts := make(map[string]int)
+	sizes := make(map[string]int64)
+	total := 0
+
+	for _, file := range files {
+		if !strings.EqualFold(path.Ext(file.Path), ".txt") {
+			continue
+		}
+		data, err := storage.Read(file.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "读取失败 %s: %v\n", file.Path, err)
+			continue
+		}
+
+		// 无法判定的文件单独归为 unknown，不套用站点默认编码
+		enc := utils.DetectEncoding(data, utils.EncodingUnknown)
+		counts[enc]++
+		sizes[enc] += int64(len(data))
+		total++
+
+		if *list != "" && strings.EqualFold(*list, enc) {
+			fmt.Println(file.Path)
+		}
+	}
+	if *list != "" {
+		return
+	}
+
+	encodings := make([]string, 0, len(counts))
+	for enc := range counts {
+		encodings = append(encodings, enc)
+	}
+	sort.Slice(encodings, func(i, j int) bool {
+		return counts[encodings[i]] > counts[encodings[j]]
+	})
+
+	fmt.Printf("前缀: %s\n", *prefix)
+	fmt.Printf("文件总数: %d\n\n", total)
+	fmt.Printf("%-12s %10s %8s %14s\n", "编码", "文件数", "占比", "总字节")
+	for _, enc := range encodings {
+		fmt.Printf("%-12s %10d %7.1f%% %14d\n", enc, counts[enc], float64(counts[enc])*100/float64(total), sizes[enc])
+	}
+}
@@ -0,0 +1,208 @@
+// main.go (importer)
+// TXT 小说导入工具
+// 将整本 TXT (GBK / UTF-8) 按标题自动切分章节，写入数据库及章节文件
+package main
+
+import (
+	"bookweb/config"
+	"bookweb/dao"
+	"bookweb/model"
+	"bookweb/utils"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// patternList 可重复指定的正则参数
+type patternList []string
+
+func (p *patternList) String() string     { return strings.Join(*p, " | ") }
+func (p *patternList) Set(v string) error { *p = append(*p, v); return nil }
+
+func main() {
+	var volumePatterns, chapterPatterns patternList
+	file := flag.String("file", "", "TXT 文件路径 (必填)")
+	title := flag.String("title", "", "小说名称 (必填)")
+	author := flag.String("author", "", "作者 (必填)")
+	sortID := flag.Int("sort", 0, "分类 ID (必填)")
+	intro := flag.String("intro", "", "内容简介")
+	full := flag.Bool("full", false, "标记为已完本")
+	encoding := flag.String("encoding", "", "文件编码 (gbk/gb18030/utf-8)，留空自动检测")
+	configPath := flag.String("config", "config/config.conf", "配置文件路径")
+	dryRun := flag.Bool("dry-run", false, "仅打印识别到的章节列表，不写入数据库和文件")
+	flag.Var(&volumePatterns, "volume-re", "分卷标题正则，可重复指定 (默认匹配 第X卷)")
+	flag.Var(&chapterPatterns, "chapter-re", "章节标题正则，可重复指定 (默认匹配 第X章 / Chapter N)")
+	flag.Parse()
+
+	if *file == "" || (!*dryRun && (*title == "" || *author == "" || *sortID <= 0)) {
+		fmt.Println("用法: go run ./cmd/importer/ -file book.txt -title 书名 -author 作者 -sort 1 [-dry-run]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	// 加载配置 (默认编码、数据库、存储)
+	appCfg, err := config.LoadAppConfig(*configPath)
+	if err != nil {
+		fmt.Println("Error: 加载配置失败:", err)
+		os.Exit(1)
+	}
+
+	// 读取并转换编码
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	enc := utils.NormalizeEncoding(*encoding)
+	if enc == "" {
+		enc = utils.DetectEncoding(data, utils.DefaultTextEncoding())
+	}
+	text, err := utils.DecodeText(data, enc)
+	if err != nil {
+		fmt.Println("Error: 转换编码失败:", err)
+		os.Exit(1)
+	}
+
+	// 切分章节
+	splitter, err := utils.NewChapterSplitter(volumePatterns, chapterPatterns)
+	if err != nil {
+		fmt.Println("Error: 标题正则错误:", err)
+		os.Exit(1)
+	}
+	chapters := splitter.Split(string(text))
+
+	fmt.Printf("文件: %s (编码: %s)\n", *file, enc)
+	if *dryRun {
+		printChapters(chapters)
+		return
+	}
+	if len(chapters) == 0 {
+		fmt.Println("Error: 未识别到任何内容")
+		os.Exit(1)
+	}
+
+	utils.InitDB(&appCfg.Db)
+	if err := utils.Db.Ping(); err != nil {
+		fmt.Println("Error: 数据库连接失败:", err)
+		os.Exit(1)
+	}
+
+	fullFlag := 0
+	if *full {
+		fullFlag = 1
+	}
+	// 导入期间小说保持隐藏，全部写入成功后再显示，失败时删除已写入的记录及文件
+	articleID, err := dao.InsertArticle(&model.Article{
+		ArticleName: *title,
+		Author:      *author,
+		SortID:      *sortID,
+		Intro:       *intro,
+		FullFlag:    fullFlag,
+		Display:     1,
+	})
+	if err != nil {
+		fmt.Println("Error: 新增小说失败:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("小说已创建 (导入完成前隐藏): articleid=%d\n", articleID)
+
+	count, written, err := importChapters(articleID, *title, chapters)
+	if err == nil {
+		if err = dao.RefreshArticleStats(articleID); err != nil {
+			err = fmt.Errorf("更新小说统计失败: %v", err)
+		}
+	}
+	if err == nil {
+		if err = dao.SetArticleDisplay(articleID, 0); err != nil {
+			err = fmt.Errorf("显示小说失败: %v", err)
+		}
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		rollbackImport(articleID, written)
+		os.Exit(1)
+	}
+
+	// 清理站点进程共享的 Redis 缓存 (小说、分类列表、首页及排行)，进程内 L1 缓存按其最长缓存时间自动过期
+	if appCfg.Redis.Enabled {
+		if err := utils.InitRedis(&appCfg.Redis); err != nil {
+			fmt.Println("Warning: Redis 连接失败，未清理缓存:", err)
+		} else {
+			dao.InvalidateArticleDependents(articleID, *sortID)
+		}
+	}
+	fmt.Printf("导入完成: %d 章, 分卷 %d 个\n", count, len(chapters)-count)
+}
+
+// importChapters 依次写入分卷、章节记录及章节文件，返回导入的章节数及已写入的文件路径
+func importChapters(articleID int, title string, chapters []utils.SplitChapter) (int, []string, error) {
+	var written []string
+	volumeID := 0
+	count := 0
+	for i, ch := range chapters {
+		order := i + 1
+		c := &model.Chapter{
+			ArticleID:    articleID,
+			ArticleName:  title,
+			VolumeID:     volumeID,
+			ChapterName:  ch.Title,
+			ChapterOrder: order,
+		}
+		if ch.IsVolume {
+			c.ChapterType = 1
+		} else {
+			c.Size = utf8.RuneCountInString(ch.Content)
+		}
+
+		chapterID, err := dao.InsertChapter(c)
+		if err != nil {
+			return count, written, fmt.Errorf("写入第 %d 章 [%s] 失败: %v", order, ch.Title, err)
+		}
+		if ch.IsVolume {
+			volumeID = chapterID
+			continue
+		}
+		path := utils.ChapterFilePath(articleID, chapterID, order)
+		if err := utils.SaveChapterFileText(articleID, chapterID, order, ch.Content); err != nil {
+			return count, written, fmt.Errorf("写入章节文件 %s 失败: %v", path, err)
+		}
+		written = append(written, path)
+		count++
+	}
+	return count, written, nil
+}
+
+// rollbackImport 导入失败时删除已写入的章节文件及小说、章节记录
+func rollbackImport(articleID int, written []string) {
+	if storage, err := utils.GetStorage(); err == nil {
+		for _, path := range written {
+			if err := storage.Delete(path); err != nil && !errors.Is(err, utils.ErrStorageNotExist) {
+				fmt.Printf("Warning: 删除章节文件 %s 失败: %v\n", path, err)
+			}
+		}
+	}
+	if err := dao.DeleteArticleAdmin(articleID); err != nil {
+		fmt.Printf("Warning: 回滚失败，请手动删除小说 %d: %v\n", articleID, err)
+		return
+	}
+	fmt.Printf("已回滚: 删除小说 %d 及 %d 个章节文件\n", articleID, len(written))
+}
+
+// printChapters 打印识别到的章节列表
+func printChapters(chapters []utils.SplitChapter) {
+	total := 0
+	fmt.Printf("%-6s %-4s %-40s %8s\n", "序号", "类型", "标题", "字数")
+	for i, ch := range chapters {
+		kind := "章节"
+		size := utf8.RuneCountInString(ch.Content)
+		if ch.IsVolume {
+			kind = "分卷"
+		}
+		total += size
+		fmt.Printf("%-6d %-4s %-40s %8d\n", i+1, kind, ch.Title, size)
+	}
+	fmt.Printf("\n共 %d 项, 总字数 %d\n", len(chapters), total)
+}
@@ -0,0 +1,153 @@
+// main.go (migratestore)
+// 存储迁移工具
+// 将章节文本和封面从一个存储后端复制到另一个 (如 local -> oss)，支持断点续传、并发、校验和及试运行
+package main
+
+import (
+	"bookweb/config"
+	"bookweb/service"
+	"bookweb/utils"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+func main() {
+	configPath := flag.String("config", "config/config.conf", "配置文件路径")
+	from := flag.String("from", "", "源存储类型 (local/oss，默认为配置中的 storage.type)")
+	to := flag.String("to", "", "目标存储类型 (local/oss，必填)")
+	fromPath := flag.String("from-path", "", "源本地存储目录 (覆盖 storage.local.path)")
+	toPath := flag.String("to-path", "", "目标本地存储目录 (覆盖 storage.local.path)")
+	articleID := flag.Int("article", 0, "仅迁移指定小说 ID (默认全站)")
+	workers := flag.Int("workers", 8, "并发数")
+	statePath := flag.String("state", "migrate-{from}-{to}.state", "进度文件，中断后重新执行会跳过已完成的文件，{from}/{to} 替换为存储类型 (为空禁用)")
+	verify := flag.Bool("verify", true, "写入后回读目标文件并比对 MD5")
+	dryRun := flag.Bool("dry-run", false, "仅统计待迁移文件，不写入目标存储")
+	switchType := flag.Bool("switch", false, "全部成功后将配置中的 storage.type 切换为目标存储")
+	flag.Parse()
+
+	if *to == "" {
+		fmt.Println("用法: go run ./cmd/migratestore/ -to oss [-from local] [-workers 8] [-dry-run] [-switch]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	appCfg, err := config.LoadAppConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 加载配置失败:", err)
+		os.Exit(1)
+	}
+	if *from == "" {
+		*from = appCfg.Storage.Type
+	}
+	if *from == "" {
+		*from = "local"
+	}
+
+	srcCfg := appCfg.Storage
+	srcCfg.Type = *from
+	if *fromPath != "" {
+		srcCfg.Local.Path = *fromPath
+	}
+	dstCfg := appCfg.Storage
+	dstCfg.Type = *to
+	if *toPath != "" {
+		dstCfg.Local.Path = *toPath
+	}
+	if srcCfg == dstCfg {
+		fmt.Fprintln(os.Stderr, "Error: 源存储与目标存储相同")
+		os.Exit(2)
+	}
+
+	*statePath = strings.NewReplacer("{from}", *from, "{to}", *to).Replace(*statePath)
+
+	src, err := utils.NewStorage(&srcCfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 源存储:", err)
+		os.Exit(1)
+	}
+	dst, err := utils.NewStorage(&dstCfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 目标存储:", err)
+		os.Exit(1)
+	}
+
+	utils.InitDB(&appCfg.Db)
+	if err := utils.Db.Ping(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 数据库连接失败:", err)
+		os.Exit(1)
+	}
+
+	paths, err := service.CollectStoragePaths(*articleID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 读取章节列表失败:", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "%s -> %s, 共 %d 个文件\n", *from, *to, len(paths))
+
+	// 进度每 5 秒输出一次 (回调由多个 worker 并发调用)
+	start := time.Now()
+	lastPrint := start
+	var mu sync.Mutex
+	progress := func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if now := time.Now(); done == total || now.Sub(lastPrint) >= 5*time.Second {
+			lastPrint = now
+			fmt.Fprintf(os.Stderr, "进度 %d/%d (%.0fs)\n", done, total, now.Sub(start).Seconds())
+		}
+	}
+
+	report, err := service.MigrateStorage(src, dst, paths, service.MigrateOptions{
+		Workers:   *workers,
+		DryRun:    *dryRun,
+		Verify:    *verify,
+		StatePath: *statePath,
+		Direction: storageLabel(&srcCfg) + " -> " + storageLabel(&dstCfg),
+	}, progress)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 迁移失败:", err)
+		os.Exit(1)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+
+	action := "复制"
+	if *dryRun {
+		action = "待复制"
+	}
+	fmt.Fprintf(os.Stderr, "%s %d (%d 字节), 跳过 %d, 源缺失 %d, 失败 %d, 耗时 %s\n",
+		action, report.Copied, report.Bytes, report.Skipped, report.Missing, report.Failed,
+		time.Since(start).Round(time.Second))
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+	if !*switchType || *dryRun {
+		return
+	}
+
+	appCfg.Storage.Type = *to
+	if *toPath != "" && *to == "local" {
+		appCfg.Storage.Local.Path = *toPath
+	}
+	if err := config.SaveAppConfig(*configPath); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: 保存配置失败:", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "已将 storage.type 切换为 %s，请重启服务生效\n", *to)
+}
+
+// storageLabel 存储的标识 (类型及本地目录 / OSS 桶)，记录在进度文件中用于识别迁移方向
+func storageLabel(cfg *config.StorageConfig) string {
+	if cfg.Type == "oss" {
+		return fmt.Sprintf("oss:%s/%s", cfg.Oss.Endpoint, cfg.Oss.Bucket)
+	}
+	return cfg.Type + ":" + cfg.Local.Path
+}
@@ -6,6 +6,9 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 )
 
@@ -49,16 +52,20 @@ type DbConfig struct {
 
 // AppConfig 应用全局配置结构
 type AppConfig struct {
-	Db        DbConfig           `json:"db"`
-	Server    ServerConfig       `json:"server"`
-	Site      SiteConfig         `json:"site"`
-	Storage   StorageConfig      `json:"storage"`
-	SeoRules  map[string]SeoRule `json:"-"`
-	Links     []LinkConfig       `json:"-"`
-	Analytics string             `json:"analytics"`
-	Redis     RedisConfig        `json:"redis"`
-	Log       LogConfig          `json:"log"`
-	Recommend RecommendConfig    `json:"recommend"`
+	Db          DbConfig           `json:"db"`
+	Server      ServerConfig       `json:"server"`
+	Site        SiteConfig         `json:"site"`
+	Storage     StorageConfig      `json:"storage"`
+	SeoRules    map[string]SeoRule `json:"-"`
+	Links       []LinkConfig       `json:"-"`
+	FilterRules []FilterRule       `json:"-"`
+	Analytics   string             `json:"analytics"`
+	Redis       RedisConfig        `json:"redis"`
+	Cache       CacheConfig        `json:"cache"`
+	Static      StaticConfig       `json:"static"`
+	Visit       VisitConfig        `json:"visit"`
+	Log         LogConfig          `json:"log"`
+	Recommend   RecommendConfig    `json:"recommend"`
 }
 
 // RecommendConfig 推荐设置
@@ -98,13 +105,83 @@ type RedisConfig struct {
 	Port     int    `json:"port"`
 	Password string `json:"password"`
 	DB       int    `json:"db"`
+	Prefix   string `json:"prefix"` // 键前缀 (如 "bookweb:")，多个站点共用同一 Redis 库时必须不同
+
+	Mode             string   `json:"mode"`              // 部署模式: single (默认) / sentinel / cluster
+	MasterName       string   `json:"master_name"`       // Sentinel 主节点名称
+	Addrs            []string `json:"addrs"`             // Sentinel 或 Cluster 节点地址 (host:port)
+	SentinelPassword string   `json:"sentinel_password"` // Sentinel 节点密码
+	DialTimeout      int      `json:"dial_timeout"`      // 连接超时 (毫秒)，0 使用默认值 5000
+	ReadTimeout      int      `json:"read_timeout"`      // 读超时 (毫秒)，0 使用默认值 3000
+	WriteTimeout     int      `json:"write_timeout"`     // 写超时 (毫秒)，0 与读超时相同
+	PoolSize         int      `json:"pool_size"`         // 连接池大小，0 使用默认值 (每 CPU 10 个)
+}
+
+// CacheConfig 进程内缓存配置
+type CacheConfig struct {
+	ContentCacheSize int            `json:"content_cache_size"` // 章节内容 LRU 缓存容量 (MB)，0 使用默认值 64，负数禁用
+	DataCacheSize    int            `json:"data_cache_size"`    // 数据查询 L1 缓存容量 (MB)，0 使用默认值 32，负数禁用
+	StaleTTL         int            `json:"stale_ttl"`          // 缓存过期后仍返回旧值并后台刷新的时间 (秒)，0 使用默认值 60，负数禁用
+	TTLJitter        int            `json:"ttl_jitter"`         // 缓存 TTL 随机抖动比例 (%)，0 使用默认值 10，负数禁用
+	PageTTL          map[string]int `json:"page_ttl"`           // 整页缓存时间 (秒)，按路由名覆盖默认值，如 {"read": 1800}
+	EpubDir          string         `json:"epub_dir"`           // EPUB 文件缓存目录，默认 cache/epub
+}
+
+// PageTTLString 整页缓存时间的文本形式 (route=秒,...)，用于后台表单
+func (c CacheConfig) PageTTLString() string {
+	names := make([]string, 0, len(c.PageTTL))
+	for name := range c.PageTTL {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + strconv.Itoa(c.PageTTL[name])
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParsePageTTL 解析 route=秒,... 形式的整页缓存时间，忽略无效项
+func ParsePageTTL(s string) map[string]int {
+	result := make(map[string]int)
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && seconds > 0 {
+			result[strings.TrimSpace(name)] = seconds
+		}
+	}
+	return result
+}
+
+// StaticConfig 静态页生成配置
+type StaticConfig struct {
+	Enabled  bool   `json:"enabled"`  // 开启后优先输出已生成的静态页
+	Dir      string `json:"dir"`      // 静态页目录，默认 cache/html
+	Interval int    `json:"interval"` // 定时增量生成间隔 (分钟)，0 为仅手动生成
+	Workers  int    `json:"workers"`  // 生成并发数，默认 4
+}
+
+// VisitConfig 点击量回写配置
+type VisitConfig struct {
+	FlushInterval int    `json:"flush_interval"`  // 回写间隔 (秒)，默认 30
+	BatchSize     int    `json:"batch_size"`      // 每条 UPDATE 更新的小说数，默认 500
+	RedisBuffer   bool   `json:"redis_buffer"`    // 多实例部署时经 Redis 汇总后回写
+	Mode          string `json:"mode"`            // 统计方式: pv (默认，每次点击)、uv (去重访客)、both (总点击量计 PV，日/周/月计 UV)
+	DedupWindow   int    `json:"dedup_window"`    // 访客去重窗口 (秒)，默认 1800
+	DedupRedis    bool   `json:"dedup_redis"`     // 去重记录保存在 Redis (多实例共享)，Redis 不可用时使用内存
+	IPHourlyLimit int    `json:"ip_hourly_limit"` // 每个 IP 每小时最多计数的点击数，0 为不限制
+	CookieSecret  string `json:"cookie_secret"`   // 访客标识 Cookie (bw_vid) 的签名密钥，多实例需一致；留空时每个进程随机生成
 }
 
 // StorageConfig 存储配置
 type StorageConfig struct {
-	Type  string      `json:"type"` // local, oss
-	Local LocalConfig `json:"local"`
-	Oss   OssConfig   `json:"oss"`
+	Type     string      `json:"type"`     // local, oss
+	Encoding string      `json:"encoding"` // 章节文本默认编码 (gbk/gb18030/utf-8)，自动检测无法判定时使用，默认 gbk
+	Local    LocalConfig `json:"local"`
+	Oss      OssConfig   `json:"oss"`
 }
 
 // LocalConfig 本地存储配置
@@ -114,17 +191,20 @@ type LocalConfig struct {
 
 // OssConfig 对象存储配置
 type OssConfig struct {
-	Endpoint  string `json:"endpoint"`
+	Endpoint  string `json:"endpoint"` // 如 oss-cn-hangzhou.aliyuncs.com 或 http://127.0.0.1:9000 (不带协议时默认 https)
 	AccessKey string `json:"access_key"`
 	SecretKey string `json:"secret_key"`
 	Bucket    string `json:"bucket"`
 	Domain    string `json:"domain"`
+	Region    string `json:"region"`     // 签名区域，默认 us-east-1
+	PathStyle bool   `json:"path_style"` // 路径模式 (endpoint/bucket/key)，MinIO 等自建服务通常需要开启
 }
 
 // ServerConfig 服务器配置结构
 type ServerConfig struct {
-	Host string `json:"host"`
-	Port int    `json:"port"`
+	Host           string   `json:"host"`
+	Port           int      `json:"port"`
+	TrustedProxies []string `json:"trusted_proxies"` // 受信任的反向代理地址 (IP 或 CIDR)，仅来自这些地址的请求才读取 X-Forwarded-For / X-Real-IP
 }
 
 // LinkConfig 友情链接配置结构
@@ -136,22 +216,28 @@ type LinkConfig struct {
 
 // SiteConfig 站点展示与 SEO 配置结构
 type SiteConfig struct {
-	SiteName       string `json:"sitename"`
-	Domain         string `json:"domain"`
-	MobileDomain   string `json:"mobile_domain"` // 移动端域名
-	Template       string `json:"template"`
-	MobileTemplate string `json:"mobile_template"`  // 移动端模板
-	AdminPath      string `json:"admin_path"`       // 后台管理路径，默认 /admin
-	SearchLimit    int    `json:"search_limit"`     // 搜索限制时间（秒）
-	IndexCache     bool   `json:"index_cache"`      // 是否开启首页缓存
-	BookCache      bool   `json:"book_cache"`       // 开启小说信息页缓存
-	BookIndexCache bool   `json:"book_index_cache"` // 开启小说目录页缓存
-	ReadCache      bool   `json:"read_cache"`       // 开启章节阅读页缓存
-	SortCache      bool   `json:"sort_cache"`       // 开启分类页缓存
-	TopCache       bool   `json:"top_cache"`        // 开启排行榜缓存
-	ForceDomain    bool   `json:"force_domain"`     // 是否强制域名访问
-	IdTransRule    string `json:"id_trans_rule"`    // 小说ID转换规则 (e.g. "*2,+100")
-	GzipEnabled    bool   `json:"gzip_enabled"`     // 开启 GZIP 压缩
+	SiteName        string `json:"sitename"`
+	Domain          string `json:"domain"`
+	MobileDomain    string `json:"mobile_domain"` // 移动端域名
+	Template        string `json:"template"`
+	MobileTemplate  string `json:"mobile_template"`  // 移动端模板
+	AdminPath       string `json:"admin_path"`       // 后台管理路径，默认 /admin
+	SearchLimit     int    `json:"search_limit"`     // 搜索限制时间（秒）
+	IndexCache      bool   `json:"index_cache"`      // 是否开启首页缓存
+	BookCache       bool   `json:"book_cache"`       // 开启小说信息页缓存
+	BookIndexCache  bool   `json:"book_index_cache"` // 开启小说目录页缓存
+	ReadCache       bool   `json:"read_cache"`       // 开启章节阅读页缓存
+	SortCache       bool   `json:"sort_cache"`       // 开启分类页缓存
+	TopCache        bool   `json:"top_cache"`        // 开启排行榜缓存
+	SearchCache     bool   `json:"search_cache"`     // 开启搜索结果页缓存
+	ForceDomain     bool   `json:"force_domain"`     // 是否强制域名访问
+	IdTransRule     string `json:"id_trans_rule"`    // 小说ID转换规则 (e.g. "*2,+100")
+	GzipEnabled     bool   `json:"gzip_enabled"`     // 开启 GZIP 压缩
+	DownloadEnabled bool   `json:"download_enabled"` // 开启全本 TXT 下载
+	DownloadLimit   int    `json:"download_limit"`   // 每个 IP 每小时下载次数限制，0 为不限制
+	VipPreviewSize  int    `json:"vip_preview_size"` // VIP 章节未购买时的试读字数
+	DailyVotes      int    `json:"daily_votes"`      // 每个用户每日推荐票数，0 为关闭推荐票
+	Timezone        string `json:"timezone"`         // 站点时区 (如 Asia/Shanghai)，日/周/月点击量及推荐票按此时区清零，为空使用系统时区
 }
 
 // SeoRule 定义单个页面的 SEO 模板
@@ -161,6 +247,15 @@ type SeoRule struct {
 	Description string `json:"description"`
 }
 
+// FilterRule 章节内容过滤规则
+type FilterRule struct {
+	Type        string `json:"type"`        // regex: 正则替换, replace: 文本替换, drop: 删除匹配行 (正则)
+	Pattern     string `json:"pattern"`     // 匹配内容
+	Replacement string `json:"replacement"` // 替换内容，为空即删除
+	Enabled     bool   `json:"enabled"`
+	Remark      string `json:"remark"` // 备注
+}
+
 // LoadRouterConfig 加载路由配置
 func LoadRouterConfig(configPath string) (*RouterConfig, error) {
 	file, err := os.Open(configPath)
@@ -199,6 +294,10 @@ func LoadAppConfig(configPath string) (*AppConfig, error) {
 	if cfg.Site.AdminPath == "" {
 		cfg.Site.AdminPath = "/admin"
 	}
+	// 未配置受信任代理时默认信任本机反向代理 (显式配置为 [] 则不信任任何代理)
+	if cfg.Server.TrustedProxies == nil {
+		cfg.Server.TrustedProxies = []string{"127.0.0.1", "::1"}
+	}
 	// 初始化推荐配置默认值
 	if cfg.Recommend.Top.Limit == 0 {
 		cfg.Recommend.Top.Sort = "allvisit"
@@ -274,6 +373,64 @@ func SaveSeoConfig(configPath string) error {
 	return os.WriteFile(configPath, data, 0644)
 }
 
+// LoadFilterConfig 加载章节内容过滤规则配置
+func LoadFilterConfig(configPath string) error {
+	file, err := os.Open(configPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var rules []FilterRule
+	decoder := json.NewDecoder(file)
+	if err := decoder.Decode(&rules); err != nil {
+		return err
+	}
+
+	configLock.Lock()
+	if GlobalConfig != nil {
+		GlobalConfig.FilterRules = rules
+	}
+	configLock.Unlock()
+	return nil
+}
+
+// SaveFilterConfig 保存章节内容过滤规则配置到文件
+func SaveFilterConfig(configPath string) error {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	if GlobalConfig == nil {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(GlobalConfig.FilterRules, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, data, 0644)
+}
+
+// UpdateFilterConfig 更新章节内容过滤规则并保存到文件 (持有配置写锁，避免与配置热重载、其它设置保存并发修改)
+func UpdateFilterConfig(configPath string, rules []FilterRule) error {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	if GlobalConfig == nil {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return err
+	}
+	GlobalConfig.FilterRules = rules
+	return nil
+}
+
 // GetRoute 获取路由路径
 func (c *RouterConfig) GetRoute(name string) string {
 	if route, ok := c.Routes[name]; ok {
@@ -11,17 +11,20 @@ import (
 	"bookweb/service"
 	"bookweb/utils"
 	"bytes"
-	"compress/gzip"
-	"fmt"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"net/http"
-	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
+	"sync"
 )
 
 // BookInfo 小说信息页面
-// BookInfo 小说信息页面
+// 整页缓存及点击量统计由 PageCache 处理 (见 pageCacheRules)
 func BookInfo(w http.ResponseWriter, r *http.Request) {
 	// 获取并校验参数
 	articleID, ok := GetIDOr404(w, r, "aid")
@@ -29,46 +32,94 @@ func BookInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 增加点击量（排除爬虫）- 移到最前以确保即使命中缓存也能统计
-	userAgent := r.UserAgent()
-	if !utils.IsBot(userAgent) {
-		go func() {
-			dao.IncArticleVisit(articleID)
-		}()
-	}
+	html, err := renderBookInfo(w, r, articleID)
+	writePageHTML(w, r, html, err)
+}
 
-	// 尝试从缓存获取整页 HTML (5分钟过期)
-	// 使用 Redis 缓存页面，极大提升并发能力
-	// 优先尝试 GZIP 缓存
-	cacheKey := fmt.Sprintf("page_cache_book_%d", articleID)
-	gzipCacheKey := cacheKey + "_gzip"
+// countArticleVisit 增加小说点击量（排除爬虫），在整页缓存之前执行以确保命中缓存也能统计
+// 点击经 IP 上限及访客去重过滤后累加内存计数，由 dao.StartVisitFlusher 定时批量回写
+// 客户端 IP 只信任直连地址或受信任代理转发的地址 (见 utils.GetClientIP)
+func countArticleVisit(w http.ResponseWriter, r *http.Request) bool {
+	articleID, ok := GetID(w, r, "aid")
+	if ok && !utils.IsBot(r.UserAgent()) {
+		ip := utils.GetClientIP(r)
+		visitor := ""
+		if service.VisitDedupEnabled() {
+			visitor = visitorID(w, r, ip)
+		}
+		service.CountArticleVisit(articleID, ip, visitor)
+	}
+	return true
+}
 
-	useGzip := strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")
+// visitorCookieName 访客标识 Cookie，值为 "访客标识.签名"
+const visitorCookieName = "bw_vid"
 
-	// 检查是否开启小说信息页缓存
-	if config.GetGlobalConfig().Site.BookCache && utils.IsRedisEnabled() {
-		if useGzip {
-			if cached, err := utils.CacheGet(gzipCacheKey); err == nil && cached != "" {
-				w.Header().Set("Content-Type", "text/html; charset=utf-8")
-				w.Header().Set("Content-Encoding", "gzip")
-				w.Write([]byte(cached))
-				return
-			}
-		}
-		// 降级尝试普通缓存
-		if cached, err := utils.CacheGet(cacheKey); err == nil && cached != "" {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			// middleware 会自动压缩
-			w.Write([]byte(cached))
-			return
+// visitorID 获取访客标识：优先使用签名有效的 Cookie，没有时按 IP+UA 生成并签名写入 Cookie
+// 首次访问与之后带 Cookie 的访问使用同一标识；Cookie 由服务端签发，客户端无法伪造新标识绕过去重，不保存 Cookie 的客户端按 IP+UA 去重
+func visitorID(w http.ResponseWriter, r *http.Request, ip string) string {
+	if c, err := r.Cookie(visitorCookieName); err == nil {
+		if id, ok := verifyVisitorCookie(c.Value); ok {
+			return id
 		}
 	}
+	sum := sha1.Sum([]byte(ip + "|" + r.UserAgent()))
+	id := hex.EncodeToString(sum[:])
+	http.SetCookie(w, &http.Cookie{
+		Name:     visitorCookieName,
+		Value:    id + "." + signVisitorID(id),
+		Path:     "/",
+		MaxAge:   365 * 24 * 3600,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
+
+// verifyVisitorCookie 校验 Cookie 签名，返回其中的访客标识
+func verifyVisitorCookie(value string) (string, bool) {
+	id, sig, ok := strings.Cut(value, ".")
+	if !ok || len(id) != sha1.Size*2 {
+		return "", false
+	}
+	if !hmac.Equal([]byte(sig), []byte(signVisitorID(id))) {
+		return "", false
+	}
+	return id, true
+}
+
+// signVisitorID 以 visit.cookie_secret 计算访客标识的 HMAC-SHA256 签名
+func signVisitorID(id string) string {
+	mac := hmac.New(sha256.New, visitorSecret())
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var (
+	visitorSecretOnce     sync.Once
+	visitorFallbackSecret []byte
+)
 
+// visitorSecret 访客 Cookie 签名密钥；未配置 visit.cookie_secret 时使用进程启动后随机生成的密钥
+// (重启或多实例之间 Cookie 不通用，访客退回按 IP+UA 识别并重新签发 Cookie)
+func visitorSecret() []byte {
+	if cfg := config.GetGlobalConfig(); cfg != nil && cfg.Visit.CookieSecret != "" {
+		return []byte(cfg.Visit.CookieSecret)
+	}
+	visitorSecretOnce.Do(func() {
+		visitorFallbackSecret = make([]byte, 32)
+		rand.Read(visitorFallbackSecret)
+		utils.LogWarn("Visit", "visit.cookie_secret is not configured, visitor cookies are signed with a random per-process key")
+	})
+	return visitorFallbackSecret
+}
+
+// renderBookInfo 渲染小说信息页
+func renderBookInfo(w http.ResponseWriter, r *http.Request, articleID int) (string, error) {
 	// 获取书籍数据
 	bookData, err := service.GetBookInfoData(articleID, "")
 	if err != nil {
-		NotFound(w, r)
-		return
+		return "", errPageNotFound
 	}
 
 	// 获取分类 Map (用于 HotArticles 显示分类名)
@@ -111,6 +162,7 @@ func BookInfo(w http.ResponseWriter, r *http.Request) {
 		Add("Article", bookData.Article).
 		Add("SortName", bookData.SortName).
 		Add("Chapters", bookData.Chapters).
+		Add("Volumes", bookData.Volumes).
 		Add("LatestChapters", bookData.LatestChapters).
 		Add("ChapterCount", len(bookData.Chapters)).
 		Add("LatestArticles", bookData.LatestArticles).
@@ -123,31 +175,12 @@ func BookInfo(w http.ResponseWriter, r *http.Request) {
 	var buf bytes.Buffer
 	t := GetRenderTemplate(w, r, "book_info.html")
 	if t == nil {
-		http.Error(w, "Template not found", http.StatusInternalServerError)
-		return
+		return "", errors.New("Template not found")
 	}
 	if err := t.Execute(&buf, data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return "", err
 	}
-
-	html := buf.String()
-	// 写入缓存 (5分钟)
-	if config.GetGlobalConfig().Site.BookCache && utils.IsRedisEnabled() {
-		utils.CacheSet(cacheKey, html, 5*time.Minute)
-
-		// 同时预生成 GZIP 缓存
-		var b bytes.Buffer
-		gz := gzip.NewWriter(&b)
-		if _, err := gz.Write([]byte(html)); err == nil {
-			if err := gz.Close(); err == nil {
-				utils.CacheSet(gzipCacheKey, b.String(), 5*time.Minute)
-			}
-		}
-	}
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(html))
+	return buf.String(), nil
 }
 
 // ChapterRead 章节阅读页面
@@ -164,7 +197,8 @@ func ChapterRead(w http.ResponseWriter, r *http.Request) {
 
 	// 1. 获取章节内容
 	chapter, err := dao.GetChapterByIDCached(chapterID)
-	if err != nil {
+	if err != nil || chapter.ChapterType == 1 || chapter.ArticleID != articleID {
+		// 分卷没有正文；章节须属于当前小说，避免绕过小说的隐藏状态
 		NotFound(w, r)
 		return
 	}
@@ -180,6 +214,18 @@ func ChapterRead(w http.ResponseWriter, r *http.Request) {
 	prevID, _ := dao.GetPrevChapterIDCached(articleID, chapter.ChapterOrder)
 	nextID, _ := dao.GetNextChapterIDCached(articleID, chapter.ChapterOrder)
 
+	// 4. VIP 章节：未购买 (含爬虫) 仅显示试读内容
+	userID := 0
+	if isLogin, sess := dao.IsLogin(r); isLogin {
+		userID = sess.UserID
+	}
+	access := service.CheckChapterAccess(chapter, userID, utils.IsBot(r.UserAgent()))
+	if access.Locked {
+		preview := *chapter
+		preview.Content = service.GetChapterPreviewHTML(chapter)
+		chapter = &preview
+	}
+
 	// 准备数据
 	// 应用标签化 SEO
 	tags := map[string]string{
@@ -191,7 +237,8 @@ func ChapterRead(w http.ResponseWriter, r *http.Request) {
 		Add("Article", article).
 		Add("Chapter", chapter).
 		Add("PrevID", prevID).
-		Add("NextID", nextID)
+		Add("NextID", nextID).
+		Add("Vip", access)
 
 	// 获取分类名称
 	sorts, _ := dao.GetAllSortsCached()
@@ -212,6 +259,9 @@ func ChapterRead(w http.ResponseWriter, r *http.Request) {
 	t.Execute(w, data)
 }
 
+// bookIndexPageSize 目录页每页章节数
+const bookIndexPageSize = 50
+
 // BookIndex 小说目录页
 func BookIndex(w http.ResponseWriter, r *http.Request) {
 	// 获取并校验参数
@@ -225,36 +275,16 @@ func BookIndex(w http.ResponseWriter, r *http.Request) {
 		page = 1
 	}
 
-	// 优先尝试 GZIP 缓存
-	cacheKey := fmt.Sprintf("page_cache_index_%d_%d", articleID, page)
-	gzipCacheKey := cacheKey + "_gzip"
-
-	useGzip := strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")
-
-	// 检查是否开启小说目录页缓存
-	if config.GetGlobalConfig().Site.BookIndexCache && utils.IsRedisEnabled() {
-		if useGzip {
-			if cached, err := utils.CacheGet(gzipCacheKey); err == nil && cached != "" {
-				w.Header().Set("Content-Type", "text/html; charset=utf-8")
-				w.Header().Set("Content-Encoding", "gzip")
-				w.Write([]byte(cached))
-				return
-			}
-		}
-		// 降级尝试普通缓存
-		if cached, err := utils.CacheGet(cacheKey); err == nil && cached != "" {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			// middleware 会自动压缩
-			w.Write([]byte(cached))
-			return
-		}
-	}
+	html, err := renderBookIndex(w, r, articleID, page)
+	writePageHTML(w, r, html, err)
+}
 
+// renderBookIndex 渲染小说目录页
+func renderBookIndex(w http.ResponseWriter, r *http.Request, articleID, page int) (string, error) {
 	// 1. 获取小说基本信息（带缓存）
 	article, err := dao.GetArticleByIDCached(articleID)
 	if err != nil {
-		NotFound(w, r)
-		return
+		return "", errPageNotFound
 	}
 
 	// 2. 获取章节目录（带缓存）
@@ -262,9 +292,11 @@ func BookIndex(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		allChapters = []*model.Chapter{}
 	}
+	volumes := service.GroupChaptersByVolume(allChapters)
+	allChapters = service.TextChapters(allChapters)
 
 	// 3. 分页逻辑
-	pageSize := 50
+	pageSize := bookIndexPageSize
 	totalCount := len(allChapters)
 	totalPage := (totalCount + pageSize - 1) / pageSize
 	if totalPage == 0 {
@@ -284,6 +316,7 @@ func BookIndex(w http.ResponseWriter, r *http.Request) {
 	if startIndex < totalCount {
 		currentChapters = allChapters[startIndex:endIndex]
 	}
+	currentVolumes := service.SliceVolumes(volumes, startIndex, endIndex)
 
 	// 生成分页页码数组
 	var pages []int
@@ -300,6 +333,7 @@ func BookIndex(w http.ResponseWriter, r *http.Request) {
 		ApplySeo("book_index", tags).
 		Add("Article", article).
 		Add("Chapters", currentChapters).
+		Add("Volumes", currentVolumes).
 		Add("ChapterCount", totalCount).
 		Add("Page", page).
 		Add("TotalPage", totalPage).
@@ -310,31 +344,12 @@ func BookIndex(w http.ResponseWriter, r *http.Request) {
 	var buf bytes.Buffer
 	t := GetRenderTemplate(w, r, "book_list.html")
 	if t == nil {
-		http.Error(w, "Template not found", http.StatusInternalServerError)
-		return
+		return "", errors.New("Template not found")
 	}
 	if err := t.Execute(&buf, data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return "", err
 	}
-
-	html := buf.String()
-	// 写入缓存 (10分钟)
-	if config.GetGlobalConfig().Site.BookIndexCache && utils.IsRedisEnabled() {
-		utils.CacheSet(cacheKey, html, 10*time.Minute)
-
-		// 同时预生成 GZIP 缓存
-		var b bytes.Buffer
-		gz := gzip.NewWriter(&b)
-		if _, err := gz.Write([]byte(html)); err == nil {
-			if err := gz.Close(); err == nil {
-				utils.CacheSet(gzipCacheKey, b.String(), 10*time.Minute)
-			}
-		}
-	}
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(html))
+	return buf.String(), nil
 }
 
 // CoverImage 处理封面图片请求
@@ -345,25 +360,35 @@ func CoverImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg := config.GetGlobalConfig()
-	// 如果配置了 OSS 且有域名，重定向到远程地址
-	if cfg != nil && cfg.Storage.Type == "oss" && cfg.Storage.Oss.Domain != "" {
-		http.Redirect(w, r, utils.GetCoverPath(articleID), http.StatusFound)
+	storage, err := utils.GetStorage()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// 本地/NFS 模式：拼接物理基准路径
 	relPath := utils.GetPhysicalCoverPath(articleID)
-	basePath := "files"
-	if cfg != nil && cfg.Storage.Local.Path != "" {
-		basePath = cfg.Storage.Local.Path
+
+	// 存储后端提供直链 (如 OSS 配置了域名)，重定向到远程地址
+	if url := storage.PublicURL(relPath); url != "" {
+		http.Redirect(w, r, url, http.StatusFound)
+		return
+	}
+
+	info, err := storage.Stat(relPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := storage.Read(relPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
 	}
-	fullPath := filepath.Join(basePath, relPath)
 
-	// 设置强缓存和 Content-Type
+	// 设置强缓存和 Content-Type；ETag/Last-Modified 供过期后重新验证，ServeContent 据此返回 304
 	w.Header().Set("Cache-Control", "public, max-age=86400")
 	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("ETag", utils.FileETag(info.ModTime, info.Size))
 
-	// 直接发送文件
-	http.ServeFile(w, r, fullPath)
+	http.ServeContent(w, r, relPath, info.ModTime, bytes.NewReader(data))
 }
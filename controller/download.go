@@ -0,0 +1,155 @@
+// download.go
+// 全本下载控制器
+// 按章节顺序流式输出整本小说的 UTF-8 TXT 文件，及 EPUB 电子书下载
+package controller
+
+import (
+	"bookweb/config"
+	"bookweb/dao"
+	"bookweb/service"
+	"bookweb/utils"
+	"bufio"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// downloadLimiter 全本下载的按 IP 频率限制 (每小时)
+var downloadLimiter = utils.NewRateLimiter(time.Hour)
+
+// BookDownload 全本 TXT 下载
+func BookDownload(w http.ResponseWriter, r *http.Request) {
+	cfg := config.GetGlobalConfig()
+	if !cfg.Site.DownloadEnabled {
+		NotFound(w, r)
+		return
+	}
+
+	articleID, ok := GetIDOr404(w, r, "aid")
+	if !ok {
+		return
+	}
+	article, err := dao.GetArticleByIDCached(articleID)
+	if err != nil || article == nil {
+		NotFound(w, r)
+		return
+	}
+
+	// HEAD 请求不输出内容，不计入下载频率
+	if r.Method != http.MethodHead && !downloadLimiter.Allow(utils.GetClientIP(r), cfg.Site.DownloadLimit) {
+		http.Error(w, "下载过于频繁，请稍后再试", http.StatusTooManyRequests)
+		return
+	}
+
+	chapters, err := dao.GetChaptersByArticleID(articleID)
+	if err != nil {
+		http.Error(w, "获取章节失败", http.StatusInternalServerError)
+		return
+	}
+
+	fileName := article.ArticleName + ".txt"
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(fileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	// 逐章读取并写出，不在内存中拼接整本内容
+	bw := bufio.NewWriterSize(w, 32*1024)
+	flusher, _ := w.(http.Flusher)
+
+	bw.WriteString(article.ArticleName + "\n")
+	bw.WriteString("作者：" + article.Author + "\n")
+
+	for _, ch := range chapters {
+		if ch.ChapterType == 1 {
+			// 分卷仅输出卷名
+			bw.WriteString("\n\n\n" + ch.ChapterName + "\n")
+			continue
+		}
+		bw.WriteString("\n\n" + ch.ChapterName + "\n\n")
+		if service.IsPaidChapter(ch) {
+			// VIP 章节不提供下载
+			bw.WriteString("　　本章为 VIP 章节，请在线订阅阅读。\n")
+			continue
+		}
+
+		content, err := utils.GetChapterFilteredText(articleID, ch.ChapterID, ch.ChapterOrder)
+		if err != nil {
+			utils.LogWarn("Download", "Failed to read chapter %d of article %d: %v", ch.ChapterID, articleID, err)
+			bw.WriteString("章节内容缺失\n")
+			continue
+		}
+		for _, line := range strings.Split(content, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				bw.WriteString("　　" + line + "\n")
+			}
+		}
+
+		if err := bw.Flush(); err != nil {
+			// 客户端已断开
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	bw.Flush()
+}
+
+// BookEpub EPUB 电子书下载
+// 生成的文件缓存在磁盘，小说更新 (lastupdate 变化) 后重新生成
+func BookEpub(w http.ResponseWriter, r *http.Request) {
+	cfg := config.GetGlobalConfig()
+	if !cfg.Site.DownloadEnabled {
+		NotFound(w, r)
+		return
+	}
+
+	articleID, ok := GetIDOr404(w, r, "aid")
+	if !ok {
+		return
+	}
+	article, err := dao.GetArticleByIDCached(articleID)
+	if err != nil || article == nil {
+		NotFound(w, r)
+		return
+	}
+
+	// HEAD 请求不输出内容，不计入下载频率
+	if r.Method != http.MethodHead && !downloadLimiter.Allow(utils.GetClientIP(r), cfg.Site.DownloadLimit) {
+		http.Error(w, "下载过于频繁，请稍后再试", http.StatusTooManyRequests)
+		return
+	}
+
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Type", "application/epub+zip")
+		w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(article.ArticleName+".epub"))
+		return
+	}
+
+	path, err := service.GetBookEpub(article)
+	if err != nil {
+		utils.LogError("Download", "Failed to build epub for article %d: %v", articleID, err)
+		http.Error(w, "生成电子书失败", http.StatusInternalServerError)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, "生成电子书失败", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "生成电子书失败", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/epub+zip")
+	w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(article.ArticleName+".epub"))
+	http.ServeContent(w, r, path, info.ModTime(), f)
+}
@@ -7,12 +7,11 @@ import (
 	"bookweb/config"
 	"bookweb/dao"
 	"bookweb/model"
-	"bookweb/utils"
 	"bytes"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 )
 
 // categoryBlock 分类块数据结构
@@ -21,19 +20,14 @@ type categoryBlock struct {
 	Articles []*model.Article
 }
 
-const indexCacheKey = "page_cache_index"
-
 // Index 处理首页请求
 func Index(w http.ResponseWriter, r *http.Request) {
-	// 尝试从缓存获取整页HTML (如果开启)
-	if config.GetGlobalConfig().Site.IndexCache {
-		if cached, err := utils.CacheGet(indexCacheKey); err == nil && cached != "" {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.Write([]byte(cached))
-			return
-		}
-	}
+	html, err := renderIndex(w, r)
+	writePageHTML(w, r, html, err)
+}
 
+// renderIndex 渲染首页
+func renderIndex(w http.ResponseWriter, r *http.Request) (string, error) {
 	data := GetCommonData(r).ApplySeo("index", nil)
 
 	// 1. 大神小说 (使用配置)
@@ -75,25 +69,15 @@ func Index(w http.ResponseWriter, r *http.Request) {
 
 	t := GetRenderTemplate(w, r, "index.html")
 	if t == nil {
-		http.Error(w, "Template not found", http.StatusInternalServerError)
-		return
+		return "", errors.New("Template not found")
 	}
 
-	// 渲染到缓冲区并缓存
+	// 渲染到缓冲区以便缓存
 	var buf bytes.Buffer
 	if err := t.Execute(&buf, data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return "", err
 	}
-
-	html := buf.String()
-	// 缓存整页HTML（1分钟过期，如果开启）
-	if config.GetGlobalConfig().Site.IndexCache {
-		utils.CacheSet(indexCacheKey, html, 1*time.Minute)
-	}
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(html))
+	return buf.String(), nil
 }
 
 // getRecommendedArticles 获取推荐小说（合并 Picks 和 Sort）
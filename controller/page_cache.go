@@ -0,0 +1,408 @@
+// page_cache.go
+// 整页缓存
+// 按路由名及参数缓存前台页面 HTML (Redis)，区分 PC/移动端，登录用户绕过；合并并发渲染，过期后返回旧页面并后台刷新
+// 信息页、目录页、阅读页按更新时间输出 ETag/Last-Modified，内容未变化时返回 304
+package controller
+
+import (
+	"bookweb/config"
+	"bookweb/dao"
+	"bookweb/model"
+	"bookweb/utils"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errPageNotFound 页面渲染时发现资源不存在，由调用方输出 404
+var errPageNotFound = errors.New("page not found")
+
+// pageCacheRule 单个路由的整页缓存规则
+type pageCacheRule struct {
+	enabled func(site *config.SiteConfig) bool                // 后台开关
+	ttl     time.Duration                                     // 默认缓存时间，可由 cache.page_ttl 按路由覆盖
+	query   []string                                          // 参与缓存键的查询参数
+	version func() string                                     // 参与缓存键的内容版本 (如章节过滤规则版本)，版本变化后旧缓存不再命中
+	tags    func(r *http.Request) []string                    // 缓存键登记的标签
+	before  func(w http.ResponseWriter, r *http.Request) bool // 每次请求 (含命中缓存) 都执行，返回 false 表示已输出响应
+	// 条件请求校验：返回页面 ETag 及最后修改时间，客户端缓存仍有效时直接返回 304 (不受缓存开关影响，仅匿名访问)
+	validate func(r *http.Request) (string, time.Time)
+}
+
+// bookPageTTL 信息页默认整页缓存时间
+const bookPageTTL = 5 * time.Minute
+
+// pageCacheRules 可缓存的前台路由
+var pageCacheRules = map[string]pageCacheRule{
+	"index": {
+		enabled: func(s *config.SiteConfig) bool { return s.IndexCache },
+		ttl:     1 * time.Minute,
+		tags:    func(r *http.Request) []string { return []string{dao.HomeTag, dao.RankTag} },
+	},
+	"book": {
+		enabled:  func(s *config.SiteConfig) bool { return s.BookCache },
+		ttl:      bookPageTTL,
+		tags:     articlePageTags,
+		before:   countArticleVisit,
+		validate: bookPageValidator,
+	},
+	"book_index": {
+		enabled:  func(s *config.SiteConfig) bool { return s.BookIndexCache },
+		ttl:      10 * time.Minute,
+		tags:     articlePageTags,
+		validate: articlePageValidator,
+	},
+	"book_index_page": {
+		enabled:  func(s *config.SiteConfig) bool { return s.BookIndexCache },
+		ttl:      10 * time.Minute,
+		tags:     articlePageTags,
+		validate: articlePageValidator,
+	},
+	"read": {
+		enabled:  func(s *config.SiteConfig) bool { return s.ReadCache },
+		ttl:      30 * time.Minute,
+		version:  utils.TextFilterVersion,
+		tags:     articlePageTags,
+		validate: chapterPageValidator,
+	},
+	"sort": {
+		enabled: func(s *config.SiteConfig) bool { return s.SortCache },
+		ttl:     10 * time.Minute,
+		tags: func(r *http.Request) []string {
+			// 分类 0 为全站列表，随首页一同失效
+			sortID, _ := utils.GetIntParam(r, "sid")
+			if sortID == 0 {
+				return []string{dao.SortTag(0), dao.HomeTag}
+			}
+			return []string{dao.SortTag(sortID)}
+		},
+	},
+	"top": {
+		enabled: func(s *config.SiteConfig) bool { return s.TopCache },
+		ttl:     5 * time.Minute,
+		tags:    func(r *http.Request) []string { return []string{dao.RankTag} },
+	},
+	"search": {
+		enabled: func(s *config.SiteConfig) bool { return s.SearchCache },
+		ttl:     3 * time.Minute,
+		query:   []string{"key", "page"},
+		before:  checkSearchLimit,
+	},
+}
+
+// articlePageTags 小说相关页面的标签
+func articlePageTags(r *http.Request) []string {
+	if id, err := utils.GetIntParam(r, "aid"); err == nil {
+		return []string{dao.ArticleTag(utils.DecodeID(id))}
+	}
+	return nil
+}
+
+// articlePageValidator 目录页以小说最后更新时间为准
+func articlePageValidator(r *http.Request) (string, time.Time) {
+	id, err := utils.GetIntParam(r, "aid")
+	if err != nil {
+		return "", time.Time{}
+	}
+	article, err := dao.GetArticleByIDCached(utils.DecodeID(id))
+	if err != nil {
+		return "", time.Time{}
+	}
+	return pageValidator(r, article.LastUpdate)
+}
+
+// bookPageValidator 信息页另含点击量、推荐票及热门、最近更新列表，ETag 除最后更新时间外加入点击及推荐票总数，
+// 并按整页缓存时间分段，列表等其它内容变化最迟在一个缓存周期后反映到客户端
+func bookPageValidator(r *http.Request) (string, time.Time) {
+	id, err := utils.GetIntParam(r, "aid")
+	if err != nil {
+		return "", time.Time{}
+	}
+	article, err := dao.GetArticleByIDCached(utils.DecodeID(id))
+	if err != nil || article.LastUpdate <= 0 {
+		return "", time.Time{}
+	}
+	terminal := "pc"
+	if IsMobile(r) {
+		terminal = "mobile"
+	}
+	ttl := int64(pageCacheTTL("book", bookPageTTL) / time.Second)
+	bucket := time.Now().Unix() / max(ttl, 1)
+	etag := utils.PageETag(r.URL.Path, terminal, strconv.FormatInt(article.LastUpdate, 10),
+		strconv.Itoa(article.AllVisit), strconv.Itoa(article.AllVote), strconv.FormatInt(bucket, 10))
+	// 不返回 Last-Modified：最后更新时间不变时内容仍可能变化，只按 ETag 校验
+	return etag, time.Time{}
+}
+
+// chapterPageValidator 阅读页取章节及小说更新时间的较大值 (新章节会改变上一章的"下一页"链接)
+func chapterPageValidator(r *http.Request) (string, time.Time) {
+	aid, err := utils.GetIntParam(r, "aid")
+	if err != nil {
+		return "", time.Time{}
+	}
+	cid, err := utils.GetIntParam(r, "cid")
+	if err != nil {
+		return "", time.Time{}
+	}
+	chapter, err := dao.GetChapterByIDCached(cid)
+	if err != nil || chapter.ArticleID != utils.DecodeID(aid) {
+		return "", time.Time{}
+	}
+	article, err := dao.GetArticleByIDCached(chapter.ArticleID)
+	if err != nil {
+		return "", time.Time{}
+	}
+	return pageValidator(r, max(chapter.LastUpdate, article.LastUpdate))
+}
+
+// pageValidator 按路径、终端及更新时间生成 ETag
+func pageValidator(r *http.Request, lastUpdate int64) (string, time.Time) {
+	if lastUpdate <= 0 {
+		return "", time.Time{}
+	}
+	terminal := "pc"
+	if IsMobile(r) {
+		terminal = "mobile"
+	}
+	return utils.PageETag(r.URL.Path, terminal, strconv.FormatInt(lastUpdate, 10)), time.Unix(lastUpdate, 0)
+}
+
+// pageCacheTTL 路由的整页缓存时间，cache.page_ttl 配置优先于默认值 def
+func pageCacheTTL(name string, def time.Duration) time.Duration {
+	if cfg := config.GetGlobalConfig(); cfg != nil {
+		if seconds := cfg.Cache.PageTTL[name]; seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return def
+}
+
+// PageCache 为路由包装整页缓存及条件请求 (304)，无缓存规则的路由原样返回
+// 仅缓存 GET/HEAD 且状态为 200 的 HTML 响应；未开启 Redis、后台未开启该页缓存或用户已登录时直接执行 handler
+func PageCache(name string, next http.HandlerFunc) http.HandlerFunc {
+	rule, ok := pageCacheRules[name]
+	if !ok {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if rule.before != nil && !rule.before(w, r) {
+			return
+		}
+
+		// 登录用户页面含用户名、书架及已购章节等个人内容，不走缓存
+		if isLogin, _ := dao.IsLogin(r); isLogin {
+			next(w, r)
+			return
+		}
+
+		if rule.validate != nil {
+			if etag, modTime := rule.validate(r); etag != "" && utils.CheckNotModified(w, r, etag, modTime) {
+				return
+			}
+		}
+
+		cfg := config.GetGlobalConfig()
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+			!utils.IsRedisEnabled() || !rule.enabled(&cfg.Site) {
+			next(w, r)
+			return
+		}
+
+		ttl := pageCacheTTL(name, rule.ttl)
+		var tags []string
+		if rule.tags != nil {
+			tags = rule.tags(r)
+		}
+
+		render := func() (string, error) {
+			rec := newPageRecorder()
+			next(rec, r)
+			if !rec.cacheable() {
+				return "", &pageResponse{rec}
+			}
+			return rec.body.String(), nil
+		}
+
+		err := servePageCached(w, r, pageCacheKey(name, rule, r), ttl, render, tags...)
+		if err != nil {
+			var resp *pageResponse
+			if errors.As(err, &resp) {
+				resp.replay(w)
+				return
+			}
+			writePageError(w, r, err)
+		}
+	}
+}
+
+// pageCacheKey 生成缓存键: page_cache:{路由}:{pc|mobile}[:v{版本}]:{路由参数}[?查询参数]
+func pageCacheKey(name string, rule pageCacheRule, r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(utils.PageCacheKeyPrefix)
+	b.WriteString(name)
+	if IsMobile(r) {
+		b.WriteString(":mobile")
+	} else {
+		b.WriteString(":pc")
+	}
+	if rule.version != nil {
+		b.WriteString(":v")
+		b.WriteString(rule.version())
+	}
+	if ps, ok := r.Context().Value(model.ParamsKey).(model.Params); ok {
+		for _, p := range ps {
+			b.WriteString(":")
+			b.WriteString(p.Value)
+		}
+	}
+	if len(rule.query) > 0 {
+		q := r.URL.Query()
+		for i, k := range rule.query {
+			if i == 0 {
+				b.WriteString("?")
+			} else {
+				b.WriteString("&")
+			}
+			b.WriteString(k)
+			b.WriteString("=")
+			b.WriteString(q.Get(k))
+		}
+	}
+	return b.String()
+}
+
+// pageRecorder 记录 handler 输出以便写入缓存
+type pageRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newPageRecorder() *pageRecorder {
+	return &pageRecorder{header: make(http.Header)}
+}
+
+func (rec *pageRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *pageRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *pageRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
+
+// cacheable 是否为可缓存的 HTML 页面
+func (rec *pageRecorder) cacheable() bool {
+	if rec.status != 0 && rec.status != http.StatusOK {
+		return false
+	}
+	if ct := rec.header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "text/html") {
+		return false
+	}
+	return rec.header.Get("Content-Encoding") == "" && rec.body.Len() > 0
+}
+
+// pageResponse 不可缓存的响应 (404、跳转、错误页等)，作为错误返回后原样回放给客户端
+type pageResponse struct {
+	rec *pageRecorder
+}
+
+func (e *pageResponse) Error() string {
+	return "page not cacheable: status " + strconv.Itoa(e.rec.status)
+}
+
+// replay 输出记录的响应；合并请求时可能回放给多个客户端，因此不回放 Set-Cookie
+func (e *pageResponse) replay(w http.ResponseWriter) {
+	for k, v := range e.rec.header {
+		if k == "Set-Cookie" {
+			continue
+		}
+		w.Header()[k] = v
+	}
+	status := e.rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(e.rec.body.Bytes())
+}
+
+// gzipString 压缩页面内容
+func gzipString(s string) (string, error) {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// servePageCached 输出整页缓存，未命中时调用 render 渲染并写入缓存
+// 客户端支持 gzip 时优先使用 {key}_gzip 预压缩缓存；render 的错误原样返回，由调用方处理
+// 两个缓存键均登记到 tags 下
+func servePageCached(w http.ResponseWriter, r *http.Request, key string, ttl time.Duration, render func() (string, error), tags ...string) error {
+	loadHTML := func() (string, error) {
+		return utils.PageLoader.Load(key, ttl, render, tags...)
+	}
+
+	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		compressed, err := utils.PageLoader.Load(key+"_gzip", ttl, func() (string, error) {
+			html, err := loadHTML()
+			if err != nil {
+				return "", err
+			}
+			return gzipString(html)
+		}, tags...)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write([]byte(compressed))
+		return nil
+	}
+
+	html, err := loadHTML()
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// middleware 会自动压缩
+	w.Write([]byte(html))
+	return nil
+}
+
+// writePageHTML 输出渲染好的页面，render 出错时输出 404 或 500
+func writePageHTML(w http.ResponseWriter, r *http.Request, html string, err error) {
+	if err != nil {
+		writePageError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(html))
+}
+
+// writePageError 输出页面渲染错误
+func writePageError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errPageNotFound) {
+		NotFound(w, r)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -12,6 +12,7 @@ import (
 )
 
 // Search 处理小说搜索请求
+// 搜索频率限制由 PageCache 在读取缓存前执行 (见 checkSearchLimit)
 func Search(w http.ResponseWriter, r *http.Request) {
 	// 1. 获取关键词和页码
 	keyword := r.URL.Query().Get("key")
@@ -27,26 +28,6 @@ func Search(w http.ResponseWriter, r *http.Request) {
 		currentPage = 1
 	}
 
-	// 限制搜索频率
-	limit := config.GetGlobalConfig().Site.SearchLimit
-	if limit > 0 {
-		cookie, err := r.Cookie("last_search_time")
-		if err == nil {
-			lastTime, _ := strconv.ParseInt(cookie.Value, 10, 64)
-			if time.Now().Unix()-lastTime < int64(limit) {
-				w.Header().Set("Content-Type", "text/html; charset=utf-8")
-				w.Write([]byte(`<script>alert("搜索过于频繁，请稍后再试");window.history.back();</script>`))
-				return
-			}
-		}
-		// 设置新的搜索时间 Cookie
-		http.SetCookie(w, &http.Cookie{
-			Name:  "last_search_time",
-			Value: strconv.FormatInt(time.Now().Unix(), 10),
-			Path:  "/",
-		})
-	}
-
 	// 2. 准备分页数据
 	pageSize := 20
 	offset := (currentPage - 1) * pageSize
@@ -91,3 +72,28 @@ func Search(w http.ResponseWriter, r *http.Request) {
 	}
 	t.Execute(w, data)
 }
+
+// checkSearchLimit 限制搜索频率，在整页缓存之前执行以确保命中缓存也受限制
+// 返回 false 表示已输出提示，无需继续处理
+func checkSearchLimit(w http.ResponseWriter, r *http.Request) bool {
+	limit := config.GetGlobalConfig().Site.SearchLimit
+	if limit <= 0 || r.URL.Query().Get("key") == "" {
+		return true
+	}
+	cookie, err := r.Cookie("last_search_time")
+	if err == nil {
+		lastTime, _ := strconv.ParseInt(cookie.Value, 10, 64)
+		if time.Now().Unix()-lastTime < int64(limit) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<script>alert("搜索过于频繁，请稍后再试");window.history.back();</script>`))
+			return false
+		}
+	}
+	// 设置新的搜索时间 Cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:  "last_search_time",
+		Value: strconv.FormatInt(time.Now().Unix(), 10),
+		Path:  "/",
+	})
+	return true
+}
@@ -4,14 +4,11 @@
 package controller
 
 import (
-	"bookweb/config"
 	"bookweb/dao"
-	"bookweb/utils"
 	"bytes"
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
 )
 
 // SortList 处理小说分类页面请求
@@ -31,16 +28,12 @@ func SortList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 尝试从缓存获取 (10分钟)
-	cacheKey := fmt.Sprintf("page_cache_sort_%d_%d", sortID, currentPage)
-	if config.GetGlobalConfig().Site.SortCache && utils.IsRedisEnabled() {
-		if cached, err := utils.CacheGet(cacheKey); err == nil && cached != "" {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			w.Write([]byte(cached))
-			return
-		}
-	}
+	html, err := renderSortList(w, r, sortID, currentPage)
+	writePageHTML(w, r, html, err)
+}
 
+// renderSortList 渲染分类列表页
+func renderSortList(w http.ResponseWriter, r *http.Request, sortID, currentPage int) (string, error) {
 	// 2. 准备基础数据
 	pageSize := 20
 	offset := (currentPage - 1) * pageSize
@@ -48,15 +41,13 @@ func SortList(w http.ResponseWriter, r *http.Request) {
 	// 获取所有分类 (侧边栏内容，带缓存)
 	allSorts, err := dao.GetAllSortsCached()
 	if err != nil {
-		NotFound(w, r)
-		return
+		return "", errPageNotFound
 	}
 
 	// 3. 获取当前分类及对应的小说列表
 	articles, err := dao.GetArticlesBySortID(sortID, offset, pageSize)
 	if err != nil {
-		NotFound(w, r)
-		return
+		return "", errPageNotFound
 	}
 
 	totalCount, err := dao.GetArticleCountBySortID(sortID)
@@ -71,8 +62,7 @@ func SortList(w http.ResponseWriter, r *http.Request) {
 			caption = currentSort.Caption
 		} else {
 			// 如果 ID 不存在，返回 404
-			NotFound(w, r)
-			return
+			return "", errPageNotFound
 		}
 	}
 
@@ -84,8 +74,7 @@ func SortList(w http.ResponseWriter, r *http.Request) {
 
 	// 严格校验：请求页码不能大于总页数
 	if currentPage > totalPage {
-		NotFound(w, r)
-		return
+		return "", errPageNotFound
 	}
 
 	// 应用标签化 SEO
@@ -109,20 +98,10 @@ func SortList(w http.ResponseWriter, r *http.Request) {
 	var buf bytes.Buffer
 	t := GetRenderTemplate(w, r, "sort.html")
 	if t == nil {
-		http.Error(w, "Template not found", http.StatusInternalServerError)
-		return
+		return "", errors.New("Template not found")
 	}
 	if err := t.Execute(&buf, data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return "", err
 	}
-
-	html := buf.String()
-	// 写入缓存 (10分钟)
-	if config.GetGlobalConfig().Site.SortCache && utils.IsRedisEnabled() {
-		utils.CacheSet(cacheKey, html, 10*time.Minute)
-	}
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(html))
+	return buf.String(), nil
 }
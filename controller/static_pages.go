@@ -0,0 +1,506 @@
+// static_pages.go
+// 静态页生成
+// 使用现有模板将信息页、目录页、阅读页预渲染为 HTML 文件 (目录结构与路由一致)，按小说更新时间增量生成；
+// 访问时优先输出已生成且未过期的文件，不存在时回退到动态渲染
+// 信息页含点击量及推荐票，投票后或超过整页缓存时间即视为过期，在后台单独重新生成
+package controller
+
+import (
+	"bookweb/config"
+	"bookweb/dao"
+	"bookweb/model"
+	"bookweb/service"
+	"bookweb/utils"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 默认值
+const (
+	DefaultStaticDir     = "cache/html"
+	DefaultStaticWorkers = 4
+)
+
+// staticStateFile 记录生成时的渲染版本及每本小说上次生成时的 lastupdate
+// 首行格式: #version\t渲染版本；其余每行格式: articleid\tlastupdate
+const staticStateFile = ".state"
+
+// staticVersionHeader 状态文件首行前缀
+const staticVersionHeader = "#version\t"
+
+// staticRoutes 生成静态页的路由
+var staticRoutes = map[string]bool{
+	"book":            true,
+	"book_index":      true,
+	"book_index_page": true,
+	"read":            true,
+}
+
+// StaticReport 静态页生成结果
+type StaticReport struct {
+	Running   bool
+	StartedAt time.Time
+	Duration  time.Duration
+	Total     int      // 可见小说总数
+	Articles  int      // 本次重新生成的小说数
+	Pages     int      // 写入的页面数
+	Failed    int      // 生成失败的小说数
+	Errors    []string // 错误信息 (最多保留 20 条)
+}
+
+const maxStaticErrors = 20
+
+var (
+	staticRunning atomic.Bool
+	staticMu      sync.Mutex
+	staticLast    StaticReport
+
+	// 磁盘上已生成页面对应的渲染版本 (模板及过滤规则)，nil 表示尚未读取状态文件
+	staticBuilt atomic.Pointer[string]
+
+	// 信息页后台重新生成，同一本书同时只执行一次
+	staticInfoFlight utils.FlightGroup
+)
+
+// staticConfig 获取静态页配置
+func staticConfig() config.StaticConfig {
+	cfg := config.GetGlobalConfig().Static
+	if cfg.Dir == "" {
+		cfg.Dir = DefaultStaticDir
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultStaticWorkers
+	}
+	return cfg
+}
+
+// staticFilePath URL 路径对应的静态文件路径，以 / 结尾的路径使用 index.html
+func staticFilePath(dir string, mobile bool, urlPath string) string {
+	variant := "pc"
+	if mobile {
+		variant = "mobile"
+	}
+	clean := path.Clean("/" + urlPath)
+	if strings.HasSuffix(urlPath, "/") {
+		clean = path.Join(clean, "index.html")
+	}
+	return filepath.Join(dir, variant, filepath.FromSlash(clean))
+}
+
+// StaticPage 为信息页、目录页、阅读页包装静态文件输出，其它路由原样返回
+// 仅匿名 GET/HEAD 请求使用；文件不存在、模板或过滤规则已变化、小说已隐藏或文件早于小说最后更新时间时回退到 next 动态渲染
+func StaticPage(name string, next http.HandlerFunc) http.HandlerFunc {
+	if !staticRoutes[name] {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := staticConfig()
+		if !cfg.Enabled || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next(w, r)
+			return
+		}
+		if isLogin, _ := dao.IsLogin(r); isLogin {
+			next(w, r)
+			return
+		}
+
+		file := staticFilePath(cfg.Dir, IsMobile(r), r.URL.Path)
+		info, err := os.Stat(file)
+		if err != nil || !staticPageFresh(name, r, info.ModTime()) {
+			next(w, r)
+			return
+		}
+		html, err := os.ReadFile(file)
+		if err != nil {
+			next(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(html)
+	}
+}
+
+// staticPageFresh 检查静态文件由当前模板及过滤规则生成，对应的小说 (及章节) 仍可访问，且文件生成时间不早于小说最后更新时间
+func staticPageFresh(name string, r *http.Request, modTime time.Time) bool {
+	if staticOutdated() {
+		return false
+	}
+	aid, err := utils.GetIntParam(r, "aid")
+	if err != nil {
+		return false
+	}
+	article, err := dao.GetArticleByIDCached(utils.DecodeID(aid))
+	if err != nil || time.Unix(article.LastUpdate, 0).After(modTime) {
+		return false
+	}
+	if name == "book" {
+		// 点击量、推荐票及热门列表随时变化：投票后或文件超过整页缓存时间时本次动态渲染，并在后台重新生成
+		if time.Unix(article.LastVote, 0).After(modTime) || time.Since(modTime) > pageCacheTTL("book", bookPageTTL) {
+			regenerateBookInfoStatic(article.ArticleID)
+			return false
+		}
+		return true
+	}
+	if name != "read" {
+		return true
+	}
+	cid, err := utils.GetIntParam(r, "cid")
+	if err != nil {
+		return false
+	}
+	// 单独编辑章节不会更新小说的 lastupdate，需同时比较章节更新时间
+	chapter, err := dao.GetChapterByIDCached(cid)
+	return err == nil && chapter.ChapterType != 1 && chapter.ArticleID == article.ArticleID &&
+		!time.Unix(chapter.LastUpdate, 0).After(modTime)
+}
+
+// staticBuiltVersion 已生成页面对应的渲染版本，从未生成时返回空字符串
+func staticBuiltVersion() string {
+	if v := staticBuilt.Load(); v != nil {
+		return *v
+	}
+	_, version, err := loadStaticState(filepath.Join(staticConfig().Dir, staticStateFile))
+	if err != nil {
+		utils.LogWarn("Static", "Read static state failed: %v", err)
+	}
+	staticBuilt.CompareAndSwap(nil, &version)
+	return *staticBuilt.Load()
+}
+
+// staticOutdated 模板或过滤规则变化后，已生成的页面全部过期
+func staticOutdated() bool {
+	return staticBuiltVersion() != utils.RenderVersion()
+}
+
+// GetStaticReport 获取最近一次 (或正在进行的) 生成结果
+func GetStaticReport() StaticReport {
+	staticMu.Lock()
+	defer staticMu.Unlock()
+	report := staticLast
+	report.Errors = append([]string(nil), staticLast.Errors...)
+	return report
+}
+
+// GenerateStaticPages 增量生成静态页：仅重新生成 lastupdate 与上次生成时不同的小说，force 为 true 时全部重新生成
+// 模板或过滤规则变化后 (渲染版本与状态文件不同) 删除旧页面并全部重新生成；同一时间只允许一个生成任务
+func GenerateStaticPages(force bool) (*StaticReport, error) {
+	if !staticRunning.CompareAndSwap(false, true) {
+		return nil, errors.New("静态页正在生成中")
+	}
+	defer staticRunning.Store(false)
+
+	cfg := staticConfig()
+	report := &StaticReport{Running: true, StartedAt: time.Now()}
+	setStaticReport(report)
+	defer func() {
+		report.Running = false
+		report.Duration = time.Since(report.StartedAt)
+		setStaticReport(report)
+	}()
+
+	articles, err := dao.GetAllArticlesForSitemap()
+	if err != nil {
+		return report, err
+	}
+	report.Total = len(articles)
+
+	statePath := filepath.Join(cfg.Dir, staticStateFile)
+	state, version, err := loadStaticState(statePath)
+	if err != nil {
+		return report, err
+	}
+	current := utils.RenderVersion()
+	if version != current {
+		// 旧页面全部过期，删除期间及重新生成完成前的访问回退到动态渲染
+		for _, variant := range []string{"pc", "mobile"} {
+			if err := os.RemoveAll(filepath.Join(cfg.Dir, variant)); err != nil {
+				return report, err
+			}
+		}
+		state = make(map[int]int64)
+	}
+	staticBuilt.Store(&current)
+
+	var todo []*model.Article
+	for _, art := range articles {
+		if force || state[art.ArticleID] != art.LastUpdate {
+			todo = append(todo, art)
+		}
+	}
+
+	var mu sync.Mutex
+	jobs := make(chan *model.Article)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for art := range jobs {
+				pages, err := generateArticleStatic(cfg.Dir, art.ArticleID)
+				mu.Lock()
+				report.Pages += pages
+				if err != nil {
+					report.Failed++
+					if len(report.Errors) < maxStaticErrors {
+						report.Errors = append(report.Errors, fmt.Sprintf("小说 %d: %v", art.ArticleID, err))
+					}
+				} else {
+					// 失败的小说不记录，下次运行时重试
+					report.Articles++
+					state[art.ArticleID] = art.LastUpdate
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, art := range todo {
+		jobs <- art
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := saveStaticState(statePath, current, state); err != nil {
+		return report, err
+	}
+	utils.LogInfo("Static", "Static pages generated: %d/%d articles, %d pages, %d failed, %s",
+		report.Articles, len(todo), report.Pages, report.Failed, time.Since(report.StartedAt).Round(time.Second))
+	return report, nil
+}
+
+// setStaticReport 保存生成结果供后台查看
+func setStaticReport(report *StaticReport) {
+	staticMu.Lock()
+	staticLast = *report
+	staticMu.Unlock()
+}
+
+// StartStaticScheduler 按配置的间隔 (分钟) 定时增量生成静态页
+// 模板或过滤规则变化导致已生成的页面过期时，不等待间隔，在下一分钟内重新生成
+func StartStaticScheduler() {
+	last := time.Now()
+	for {
+		time.Sleep(time.Minute)
+		cfg := staticConfig()
+		if !cfg.Enabled {
+			continue
+		}
+		outdated := staticBuiltVersion() != "" && staticOutdated()
+		due := cfg.Interval > 0 && time.Since(last) >= time.Duration(cfg.Interval)*time.Minute
+		if !outdated && !due {
+			continue
+		}
+		last = time.Now()
+		if _, err := GenerateStaticPages(false); err != nil {
+			utils.LogWarn("Static", "Scheduled static generation failed: %v", err)
+		}
+	}
+}
+
+// RegenerateArticleStatic 在后台重新生成一本小说的静态页
+// 用于小说内容变化但 lastupdate 未更新的操作 (如显示 / 隐藏章节)，此时静态页无法按时间判断是否过期
+func RegenerateArticleStatic(articleID int) {
+	cfg := staticConfig()
+	if !cfg.Enabled {
+		return
+	}
+	go func() {
+		if _, err := generateArticleStatic(cfg.Dir, articleID); err != nil {
+			utils.LogWarn("Static", "Regenerate static pages for article %d failed: %v", articleID, err)
+		}
+	}()
+}
+
+// regenerateBookInfoStatic 在后台重新生成一本小说的信息页
+func regenerateBookInfoStatic(articleID int) {
+	staticInfoFlight.DoAsync(strconv.Itoa(articleID), func() (string, error) {
+		aid := strconv.Itoa(utils.EncodeID(articleID))
+		for _, mobile := range staticVariants() {
+			if err := writeStaticPage(staticConfig().Dir, BookInfo, utils.BookUrl(articleID), model.Params{{Key: "aid", Value: aid}}, mobile); err != nil {
+				return "", fmt.Errorf("article %d info page: %w", articleID, err)
+			}
+		}
+		return "", nil
+	})
+}
+
+// staticVariants 需要生成的终端 (false 为 PC)，配置了移动端模板时同时生成移动端页面
+func staticVariants() []bool {
+	if config.GetGlobalConfig().Site.MobileTemplate != "" {
+		return []bool{false, true}
+	}
+	return []bool{false}
+}
+
+// generateArticleStatic 生成一本小说的信息页、目录页 (含分页) 及所有章节阅读页，返回写入的页面数
+// 配置了移动端模板时同时生成移动端页面
+func generateArticleStatic(dir string, articleID int) (int, error) {
+	chapters, err := dao.GetChaptersByArticleIDCached(articleID)
+	if err != nil {
+		return 0, err
+	}
+	chapters = service.TextChapters(chapters)
+
+	totalPage := (len(chapters) + bookIndexPageSize - 1) / bookIndexPageSize
+	if totalPage == 0 {
+		totalPage = 1
+	}
+
+	aid := strconv.Itoa(utils.EncodeID(articleID))
+	pages := 0
+	for _, mobile := range staticVariants() {
+		render := func(handler http.HandlerFunc, urlPath string, params model.Params) error {
+			if err := writeStaticPage(dir, handler, urlPath, params, mobile); err != nil {
+				return fmt.Errorf("%s: %w", urlPath, err)
+			}
+			pages++
+			return nil
+		}
+
+		if err := render(BookInfo, utils.BookUrl(articleID), model.Params{{Key: "aid", Value: aid}}); err != nil {
+			return pages, err
+		}
+		if err := render(BookIndex, utils.BookIndexUrl(articleID), model.Params{{Key: "aid", Value: aid}}); err != nil {
+			return pages, err
+		}
+		for page := 1; page <= totalPage; page++ {
+			params := model.Params{{Key: "aid", Value: aid}, {Key: "page", Value: strconv.Itoa(page)}}
+			if err := render(BookIndex, utils.BookIndexPageUrl(articleID, page), params); err != nil {
+				return pages, err
+			}
+		}
+		for _, ch := range chapters {
+			params := model.Params{{Key: "aid", Value: aid}, {Key: "cid", Value: strconv.Itoa(ch.ChapterID)}}
+			if err := render(ChapterRead, utils.ReadUrl(articleID, ch.ChapterID), params); err != nil {
+				return pages, err
+			}
+		}
+	}
+	return pages, nil
+}
+
+// writeStaticPage 以匿名访客身份执行 handler 并将 200 响应写入静态文件 (先写临时文件再重命名)
+func writeStaticPage(dir string, handler http.HandlerFunc, urlPath string, params model.Params, mobile bool) error {
+	site := config.GetGlobalConfig().Site
+	host := site.Domain
+	if mobile && site.MobileDomain != "" {
+		host = site.MobileDomain
+	}
+	if host == "" {
+		host = "localhost"
+	}
+
+	r, err := http.NewRequest(http.MethodGet, "http://"+host+urlPath, nil)
+	if err != nil {
+		return err
+	}
+	if mobile {
+		r.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 10; Mobile) bookweb-static")
+	} else {
+		r.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0) bookweb-static")
+	}
+	ctx := context.WithValue(r.Context(), model.ParamsKey, params)
+	ctx = context.WithValue(ctx, model.StartTimeKey, time.Now())
+
+	rec := newPageRecorder()
+	handler(rec, r.WithContext(ctx))
+	if !rec.cacheable() {
+		return fmt.Errorf("status %d", rec.status)
+	}
+
+	file := staticFilePath(dir, mobile, urlPath)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	// 临时文件名唯一，后台单本重新生成可能与批量生成同时写入同一页面
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(rec.body.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// loadStaticState 读取生成状态及生成时的渲染版本，文件不存在时返回空状态
+func loadStaticState(statePath string) (map[int]int64, string, error) {
+	state := make(map[int]int64)
+	f, err := os.Open(statePath)
+	if os.IsNotExist(err) {
+		return state, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	version := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, staticVersionHeader) {
+			version = strings.TrimPrefix(line, staticVersionHeader)
+			continue
+		}
+		idStr, lastStr, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		id, err1 := strconv.Atoi(idStr)
+		last, err2 := strconv.ParseInt(lastStr, 10, 64)
+		if err1 == nil && err2 == nil {
+			state[id] = last
+		}
+	}
+	return state, version, scanner.Err()
+}
+
+// saveStaticState 写入生成状态
+func saveStaticState(statePath string, version string, state map[int]int64) error {
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return err
+	}
+	ids := make([]int, 0, len(state))
+	for id := range state {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var b strings.Builder
+	b.WriteString(staticVersionHeader)
+	b.WriteString(version)
+	b.WriteString("\n")
+	for _, id := range ids {
+		b.WriteString(strconv.Itoa(id))
+		b.WriteString("\t")
+		b.WriteString(strconv.FormatInt(state[id], 10))
+		b.WriteString("\n")
+	}
+	tmp := statePath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, statePath)
+}
@@ -46,6 +46,11 @@ func UserCenter(w http.ResponseWriter, r *http.Request) {
 	if user != nil {
 		data.Add("User", user)
 
+		// 钱包余额
+		if balance, err := dao.GetWalletBalance(user.Id); err == nil {
+			data.Add("WalletBalance", balance)
+		}
+
 		// 获取书架
 		bookcases, _, err := service.GetBookcaseList(user.Id, 1, 100)
 		if err == nil {
@@ -0,0 +1,43 @@
+// vip.go
+// VIP 章节控制器
+// 处理 VIP 章节的购买请求
+package controller
+
+import (
+	"bookweb/dao"
+	"bookweb/service"
+	"bookweb/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// ChapterBuy 购买 VIP 章节
+func ChapterBuy(w http.ResponseWriter, r *http.Request) {
+	isLogin, sess := dao.IsLogin(r)
+	w.Header().Set("Content-Type", "application/json")
+	if !isLogin {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "请先登录"})
+		return
+	}
+
+	chapterID, _ := strconv.Atoi(r.PostFormValue("chapterid"))
+	if chapterID <= 0 {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "无效的章节ID"})
+		return
+	}
+
+	balance, err := service.BuyChapter(sess.UserID, chapterID)
+	if err != nil {
+		message := err.Error()
+		if !errors.Is(err, dao.ErrChapterNotFound) && !errors.Is(err, dao.ErrChapterNotForSale) &&
+			!errors.Is(err, dao.ErrAlreadyPurchased) && !errors.Is(err, dao.ErrInsufficientBalance) {
+			utils.LogError("VIP", "Buy chapter %d for user %d failed: %v", chapterID, sess.UserID, err)
+			message = "购买失败，请稍后再试"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": message, "balance": balance})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "购买成功", "balance": balance})
+}
@@ -0,0 +1,50 @@
+// vote.go
+// 推荐票控制器
+// 处理登录用户为小说投推荐票的请求
+package controller
+
+import (
+	"bookweb/dao"
+	"bookweb/service"
+	"bookweb/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// BookVote 投推荐票
+func BookVote(w http.ResponseWriter, r *http.Request) {
+	isLogin, sess := dao.IsLogin(r)
+	w.Header().Set("Content-Type", "application/json")
+	if !isLogin {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "请先登录"})
+		return
+	}
+
+	articleID, _ := strconv.Atoi(r.PostFormValue("articleid"))
+	// ID 转换
+	articleID = utils.DecodeID(articleID)
+	if articleID <= 0 {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "无效的文章ID"})
+		return
+	}
+
+	remaining, err := service.VoteArticle(sess.UserID, articleID)
+	if err != nil {
+		message := err.Error()
+		if !errors.Is(err, dao.ErrVoteLimit) && !errors.Is(err, service.ErrVoteDisabled) &&
+			!errors.Is(err, service.ErrVoteArticleNotFound) {
+			// 数据库等内部错误只记录日志，不返回给用户
+			utils.LogError("Vote", "Vote for article %d by user %d failed: %v", articleID, sess.UserID, err)
+			message = "投票失败，请稍后再试"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": message})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"message":   "投票成功，今日还剩 " + strconv.Itoa(remaining) + " 票",
+		"remaining": remaining,
+	})
+}
@@ -29,7 +29,7 @@ func GetArticleListAdmin(page, pageSize int, keyword string) ([]*model.Article,
 
 	// 查询列表
 	querySQL := `SELECT articleid, articlename, author, sortid, intro, fullflag, 
-		lastupdate, size, allvisit FROM jieqi_article_article WHERE 1=1`
+		lastupdate, size, allvisit, display FROM jieqi_article_article WHERE 1=1`
 	if keyword != "" {
 		querySQL += " AND (articlename LIKE ? OR author LIKE ?)"
 	}
@@ -46,7 +46,7 @@ func GetArticleListAdmin(page, pageSize int, keyword string) ([]*model.Article,
 	for rows.Next() {
 		a := &model.Article{}
 		err := rows.Scan(&a.ArticleID, &a.ArticleName, &a.Author, &a.SortID,
-			&a.Intro, &a.FullFlag, &a.LastUpdate, &a.Size, &a.AllVisit)
+			&a.Intro, &a.FullFlag, &a.LastUpdate, &a.Size, &a.AllVisit, &a.Display)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -81,13 +81,68 @@ func DeleteArticleAdmin(id int) error {
 
 // GetArticleByIDAdmin 根据 ID 获取小说（后台用）
 func GetArticleByIDAdmin(id int) (*model.Article, error) {
-	sqlStr := `SELECT articleid, articlename, author, sortid, intro, fullflag 
+	sqlStr := `SELECT articleid, articlename, author, sortid, intro, fullflag, display 
 		FROM jieqi_article_article WHERE articleid = ?`
 	row := utils.Db.QueryRow(sqlStr, id)
 	a := &model.Article{}
-	err := row.Scan(&a.ArticleID, &a.ArticleName, &a.Author, &a.SortID, &a.Intro, &a.FullFlag)
+	err := row.Scan(&a.ArticleID, &a.ArticleName, &a.Author, &a.SortID, &a.Intro, &a.FullFlag, &a.Display)
 	if err != nil {
 		return nil, err
 	}
 	return a, nil
 }
+
+// GetChapterListAdmin 获取小说的全部章节（后台用，不含内容）
+func GetChapterListAdmin(articleID int) ([]*model.Chapter, error) {
+	sqlStr := `SELECT chapterid, articleid, chaptername, chapterorder, size, lastupdate, 
+		isvip, chaptertype, display FROM jieqi_article_chapter WHERE articleid = ? ORDER BY chapterorder ASC`
+	rows, err := utils.Db.Query(sqlStr, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chapters []*model.Chapter
+	for rows.Next() {
+		c := &model.Chapter{}
+		err := rows.Scan(&c.ChapterID, &c.ArticleID, &c.ChapterName, &c.ChapterOrder, &c.Size,
+			&c.LastUpdate, &c.IsVIP, &c.ChapterType, &c.Display)
+		if err != nil {
+			return nil, err
+		}
+		chapters = append(chapters, c)
+	}
+	return chapters, nil
+}
+
+// GetChapterByIDAdmin 根据 ID 获取章节元数据（后台用，不含内容）
+func GetChapterByIDAdmin(id int) (*model.Chapter, error) {
+	sqlStr := `SELECT chapterid, articleid, articlename, chaptername, chapterorder, size, lastupdate, 
+		isvip, chaptertype, display FROM jieqi_article_chapter WHERE chapterid = ?`
+	c := &model.Chapter{}
+	err := utils.Db.QueryRow(sqlStr, id).Scan(&c.ChapterID, &c.ArticleID, &c.ArticleName, &c.ChapterName,
+		&c.ChapterOrder, &c.Size, &c.LastUpdate, &c.IsVIP, &c.ChapterType, &c.Display)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// UpdateChapterAdmin 更新章节标题与字数
+func UpdateChapterAdmin(id int, name string, size int) error {
+	sqlStr := "UPDATE jieqi_article_chapter SET chaptername = ?, size = ?, lastupdate = UNIX_TIMESTAMP() WHERE chapterid = ?"
+	_, err := utils.Db.Exec(sqlStr, name, size, id)
+	return err
+}
+
+// SetArticleDisplay 设置小说显示状态 (0 显示，1 隐藏)
+func SetArticleDisplay(id, display int) error {
+	_, err := utils.Db.Exec("UPDATE jieqi_article_article SET display = ? WHERE articleid = ?", display, id)
+	return err
+}
+
+// SetChapterDisplay 设置章节显示状态 (0 显示，1 隐藏)
+func SetChapterDisplay(id, display int) error {
+	_, err := utils.Db.Exec("UPDATE jieqi_article_chapter SET display = ? WHERE chapterid = ?", display, id)
+	return err
+}
@@ -7,18 +7,17 @@ import (
 	"bookweb/model"
 	"bookweb/utils"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"time"
 )
 
-// GetArticleByID 根据ArticleID获取小说信息
+// GetArticleByID 根据ArticleID获取小说信息，隐藏 (display 非 0) 的小说返回 sql.ErrNoRows
 func GetArticleByID(id int) (*model.Article, error) {
 	var row *sql.Row
 	if stmtGetArticleByID != nil {
 		row = stmtGetArticleByID.QueryRow(id)
 	} else {
-		sqlStr := "select articleid, siteid, postdate, lastupdate, articlename, keywords, initial, authorid, author, posterid, poster, agentid, agent, sortid, typeid, intro, notice, setting, lastvolumeid, lastvolume, lastchapterid, lastchapter, chapters, size, lastvisit, dayvisit, weekvisit, monthvisit, allvisit, lastvote, dayvote, weekvote, monthvote, allvote, fullflag, imgflag from jieqi_article_article where articleid = ?"
+		sqlStr := "select articleid, siteid, postdate, lastupdate, articlename, keywords, initial, authorid, author, posterid, poster, agentid, agent, sortid, typeid, intro, notice, setting, lastvolumeid, lastvolume, lastchapterid, lastchapter, chapters, size, lastvisit, dayvisit, weekvisit, monthvisit, allvisit, lastvote, dayvote, weekvote, monthvote, allvote, fullflag, imgflag from jieqi_article_article where articleid = ? and display = 0"
 		row = utils.Db.QueryRow(sqlStr, id)
 	}
 	art := &model.Article{}
@@ -29,14 +28,14 @@ func GetArticleByID(id int) (*model.Article, error) {
 	return art, nil
 }
 
-// GetChaptersByArticleID 根据ArticleID获取章节列表(不含内容)
+// GetChaptersByArticleID 根据ArticleID获取章节列表(不含内容)，包含分卷 (chaptertype=1)，不含隐藏章节
 func GetChaptersByArticleID(articleID int) ([]*model.Chapter, error) {
 	var rows *sql.Rows
 	var err error
 	if stmtGetChaptersByArticle != nil {
 		rows, err = stmtGetChaptersByArticle.Query(articleID)
 	} else {
-		sqlStr := "select chapterid, chaptername, chapterorder, isvip, size, lastupdate from jieqi_article_chapter where articleid = ? order by chapterorder asc"
+		sqlStr := "select chapterid, volumeid, chaptername, chapterorder, isvip, saleprice, size, lastupdate, chaptertype from jieqi_article_chapter where articleid = ? and display = 0 order by chapterorder asc"
 		rows, err = utils.Db.Query(sqlStr, articleID)
 	}
 	if err != nil {
@@ -47,7 +46,7 @@ func GetChaptersByArticleID(articleID int) ([]*model.Chapter, error) {
 	var chapters []*model.Chapter
 	for rows.Next() {
 		ch := &model.Chapter{}
-		err := rows.Scan(&ch.ChapterID, &ch.ChapterName, &ch.ChapterOrder, &ch.IsVIP, &ch.Size, &ch.LastUpdate)
+		err := rows.Scan(&ch.ChapterID, &ch.VolumeID, &ch.ChapterName, &ch.ChapterOrder, &ch.IsVIP, &ch.SalePrice, &ch.Size, &ch.LastUpdate, &ch.ChapterType)
 		if err != nil {
 			return nil, err
 		}
@@ -59,10 +58,10 @@ func GetChaptersByArticleID(articleID int) ([]*model.Chapter, error) {
 // GetArticlesBySortID 分页获取指定分类的小说列表
 // 如果 sortID 为 0，则获取全部分类
 func GetArticlesBySortID(sortID int, offset, limit int) ([]*model.Article, error) {
-	sqlStr := "select articleid, articlename, author, intro, size, lastupdate, sortid, fullflag, imgflag, lastchapterid, lastchapter from jieqi_article_article"
+	sqlStr := "select articleid, articlename, author, intro, size, lastupdate, sortid, fullflag, imgflag, lastchapterid, lastchapter from jieqi_article_article where display = 0"
 	var args []interface{}
 	if sortID > 0 {
-		sqlStr += " where sortid = ?"
+		sqlStr += " and sortid = ?"
 		args = append(args, sortID)
 	}
 	sqlStr += " order by lastupdate desc limit ?, ?"
@@ -89,31 +88,9 @@ func GetArticlesBySortID(sortID int, offset, limit int) ([]*model.Article, error
 
 // GetArticlesBySortIDCached 带缓存分页获取指定分类的小说列表
 func GetArticlesBySortIDCached(sortID int, offset, limit int) ([]*model.Article, error) {
-	cacheKey := fmt.Sprintf("articles_sort_%d_%d_%d", sortID, offset, limit)
-
-	if !utils.IsRedisEnabled() {
+	return getCached(fmt.Sprintf("articles_sort_%d_%d_%d", sortID, offset, limit), 5*time.Minute, func() ([]*model.Article, error) {
 		return GetArticlesBySortID(sortID, offset, limit)
-	}
-
-	// 尝试从 Redis 缓存获取
-	if cached, err := utils.CacheGet(cacheKey); err == nil && cached != "" {
-		var articles []*model.Article
-		if err := json.Unmarshal([]byte(cached), &articles); err == nil {
-			return articles, nil
-		}
-	}
-
-	// 从数据库获取
-	articles, err := GetArticlesBySortID(sortID, offset, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	// 写入缓存
-	if data, err := json.Marshal(articles); err == nil {
-		utils.CacheSet(cacheKey, string(data), 5*time.Minute)
-	}
-	return articles, nil
+	}, sortListTags(sortID)...)
 }
 
 // GetArticlesByIDs 批量获取小说信息
@@ -123,7 +100,7 @@ func GetArticlesByIDs(ids []int) ([]*model.Article, error) {
 	}
 
 	// 构建 IN 查询
-	sqlStr := "select articleid, articlename, author, intro, size, lastupdate, sortid, fullflag, imgflag, lastchapterid, lastchapter from jieqi_article_article where articleid in ("
+	sqlStr := "select articleid, articlename, author, intro, size, lastupdate, sortid, fullflag, imgflag, lastchapterid, lastchapter from jieqi_article_article where display = 0 and articleid in ("
 	args := make([]interface{}, len(ids))
 	for i, id := range ids {
 		if i > 0 {
@@ -162,10 +139,10 @@ func GetArticlesByIDs(ids []int) ([]*model.Article, error) {
 
 // GetArticleCountBySortID 获取指定分类的小说总数
 func GetArticleCountBySortID(sortID int) (int, error) {
-	sqlStr := "select count(*) from jieqi_article_article"
+	sqlStr := "select count(*) from jieqi_article_article where display = 0"
 	var args []interface{}
 	if sortID > 0 {
-		sqlStr += " where sortid = ?"
+		sqlStr += " and sortid = ?"
 		args = append(args, sortID)
 	}
 
@@ -181,7 +158,7 @@ func GetVisitArticles(limit int) ([]*model.Article, error) {
 	if stmtGetVisitArticles != nil {
 		rows, err = stmtGetVisitArticles.Query(limit)
 	} else {
-		sqlStr := "select articleid, articlename, author, intro, size, lastupdate, sortid, fullflag, imgflag, lastchapterid, lastchapter from jieqi_article_article order by allvisit desc limit ?"
+		sqlStr := "select articleid, articlename, author, intro, size, lastupdate, sortid, fullflag, imgflag, lastchapterid, lastchapter from jieqi_article_article where display = 0 order by allvisit desc limit ?"
 		rows, err = utils.Db.Query(sqlStr, limit)
 	}
 	if err != nil {
@@ -203,29 +180,9 @@ func GetVisitArticles(limit int) ([]*model.Article, error) {
 
 // GetVisitArticlesCached 带缓存获取按点击量排序的小说列表
 func GetVisitArticlesCached(limit int) ([]*model.Article, error) {
-	cacheKey := fmt.Sprintf("visit_articles_%d", limit)
-
-	if !utils.IsRedisEnabled() {
+	return getCached(fmt.Sprintf("visit_articles_%d", limit), 5*time.Minute, func() ([]*model.Article, error) {
 		return GetVisitArticles(limit)
-	}
-
-	// 尝试从 Redis 缓存获取
-	if cached, err := utils.CacheGet(cacheKey); err == nil && cached != "" {
-		var articles []*model.Article
-		if err := json.Unmarshal([]byte(cached), &articles); err == nil {
-			return articles, nil
-		}
-	}
-
-	articles, err := GetVisitArticles(limit)
-	if err != nil {
-		return nil, err
-	}
-
-	if data, err := json.Marshal(articles); err == nil {
-		utils.CacheSet(cacheKey, string(data), 5*time.Minute)
-	}
-	return articles, nil
+	}, RankTag, HomeTag)
 }
 
 // GetArticlesBySortAndOrder 获取分类小说列表 (按指定字段排序)
@@ -267,29 +224,9 @@ func GetArticlesBySortAndOrder(sortID int, orderBy string, limit int) ([]*model.
 
 // GetArticlesBySortAndOrderCached 带缓存获取分类小说列表
 func GetArticlesBySortAndOrderCached(sortID int, order string, limit int) ([]*model.Article, error) {
-	cacheKey := fmt.Sprintf("articles_sort_%d_%s_%d", sortID, order, limit)
-
-	if !utils.IsRedisEnabled() {
+	return getCached(fmt.Sprintf("articles_sort_%d_%s_%d", sortID, order, limit), 10*time.Minute, func() ([]*model.Article, error) {
 		return GetArticlesBySortAndOrder(sortID, order, limit)
-	}
-
-	// 尝试从 Redis 缓存获取
-	if cached, err := utils.CacheGet(cacheKey); err == nil && cached != "" {
-		var articles []*model.Article
-		if err := json.Unmarshal([]byte(cached), &articles); err == nil {
-			return articles, nil
-		}
-	}
-
-	articles, err := GetArticlesBySortAndOrder(sortID, order, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	if data, err := json.Marshal(articles); err == nil {
-		utils.CacheSet(cacheKey, string(data), 10*time.Minute)
-	}
-	return articles, nil
+	}, sortListTags(sortID, RankTag)...)
 }
 
 // GetRankArticles 获取按指定字段排序的小说列表
@@ -312,7 +249,7 @@ func GetRankArticles(orderBy string, limit int) ([]*model.Article, error) {
 		orderBy = "allvisit"
 	}
 
-	sqlStr := "select articleid, articlename, author, intro, size, lastupdate, sortid, fullflag, imgflag, lastchapterid, lastchapter from jieqi_article_article order by " + orderBy + " desc limit ?"
+	sqlStr := "select articleid, articlename, author, intro, size, lastupdate, sortid, fullflag, imgflag, lastchapterid, lastchapter from jieqi_article_article where display = 0 order by " + orderBy + " desc limit ?"
 	rows, err := utils.Db.Query(sqlStr, limit)
 	if err != nil {
 		return nil, err
@@ -333,7 +270,7 @@ func GetRankArticles(orderBy string, limit int) ([]*model.Article, error) {
 
 // SearchArticles 模糊查询小说列表
 func SearchArticles(keyword string, offset, limit int) ([]*model.Article, error) {
-	sqlStr := "select articleid, articlename, author, intro, size, lastupdate, sortid, fullflag, imgflag, lastchapterid, lastchapter from jieqi_article_article where articlename like ? or author like ? order by lastupdate desc limit ?, ?"
+	sqlStr := "select articleid, articlename, author, intro, size, lastupdate, sortid, fullflag, imgflag, lastchapterid, lastchapter from jieqi_article_article where display = 0 and (articlename like ? or author like ?) order by lastupdate desc limit ?, ?"
 	rows, err := utils.Db.Query(sqlStr, "%"+keyword+"%", "%"+keyword+"%", offset, limit)
 	if err != nil {
 		return nil, err
@@ -354,155 +291,12 @@ func SearchArticles(keyword string, offset, limit int) ([]*model.Article, error)
 
 // GetSearchCount 获取模糊查询的结果总数
 func GetSearchCount(keyword string) (int, error) {
-	sqlStr := "select count(*) from jieqi_article_article where articlename like ? or author like ?"
+	sqlStr := "select count(*) from jieqi_article_article where display = 0 and (articlename like ? or author like ?)"
 	var count int
 	err := utils.Db.QueryRow(sqlStr, "%"+keyword+"%", "%"+keyword+"%").Scan(&count)
 	return count, err
 }
 
-// IncArticleVisit 增加文章点击量 (优化版：Redis 缓冲 + 批量回写)
-func IncArticleVisit(id int) error {
-	const FlushThreshold = 10
-
-	// 如果开启了 Redis，先尝试在 Redis 中缓冲
-	if utils.IsRedisEnabled() {
-		bufferKey := fmt.Sprintf("article:visit_buffer:%d", id)
-
-		// 1. 增加缓冲区计数
-		val, err := utils.CacheIncr(bufferKey)
-		if err != nil {
-			// 如果 Redis 操作失败，记录错误并降级到直接写库 (这里简化处理，直接返回错误或继续)
-			// 为保证数据一致性，如果 Redis 挂了，这里可以选择降级
-			utils.LogError("Redis", "Redis Incr failed: %v", err)
-		} else {
-			// 如果未达到阈值，直接返回，不写库
-			if val < FlushThreshold {
-				return nil
-			}
-
-			// 达到阈值，获取并重置缓冲区
-			// 使用 GetSet 原子性地重置为 0 并拿到旧值
-			oldValStr, err := utils.CacheGetSet(bufferKey, 0)
-			if err != nil {
-				return err
-			}
-
-			// 解析增加的访问量
-			delta := 0
-			fmt.Sscanf(oldValStr, "%d", &delta)
-
-			// 如果 delta <= 0 说明可能并发重置了，或者刚重置完
-			if delta <= 0 {
-				return nil
-			}
-
-			// 2. 准备回写数据库
-			article, err := GetArticleByID(id)
-			if err != nil {
-				return err
-			}
-
-			now := utils.NowTime()
-			lastVisit := article.LastVisit
-
-			// 3. 判断是否需要重置 (按时间)
-			resetDay := !utils.IsSameDay(lastVisit, now)
-			resetWeek := !utils.IsSameWeek(lastVisit, now)
-			resetMonth := !utils.IsSameMonth(lastVisit, now)
-
-			// 4. 构建 SQL
-			sqlStr := "update jieqi_article_article set allvisit=allvisit+?, lastvisit=?"
-			args := []interface{}{delta, now}
-
-			if resetDay {
-				sqlStr += ", dayvisit=?"
-				args = append(args, delta)
-			} else {
-				sqlStr += ", dayvisit=dayvisit+?"
-				args = append(args, delta)
-			}
-
-			if resetWeek {
-				sqlStr += ", weekvisit=?"
-				args = append(args, delta)
-			} else {
-				sqlStr += ", weekvisit=weekvisit+?"
-				args = append(args, delta)
-			}
-
-			if resetMonth {
-				sqlStr += ", monthvisit=?"
-				args = append(args, delta)
-			} else {
-				sqlStr += ", monthvisit=monthvisit+?"
-				args = append(args, delta)
-			}
-
-			sqlStr += " where articleid=?"
-			args = append(args, id)
-
-			_, err = utils.Db.Exec(sqlStr, args...)
-
-			// 5. 假如写入成功，清理相关页面缓存
-			if err == nil {
-				InvalidateArticleCache(id)
-			}
-
-			return err
-		}
-	}
-
-	// === 降级处理 / 未开启 Redis 的原有逻辑 ===
-
-	// 1. 获取当前点击量信息
-	article, err := GetArticleByID(id)
-	if err != nil {
-		return err
-	}
-
-	now := utils.NowTime()
-	lastVisit := article.LastVisit
-
-	// 2. 判断是否需要重置
-	resetDay := !utils.IsSameDay(lastVisit, now)
-	resetWeek := !utils.IsSameWeek(lastVisit, now)
-	resetMonth := !utils.IsSameMonth(lastVisit, now)
-
-	// 3. 更新点击量
-	sqlStr := "update jieqi_article_article set allvisit=allvisit+1, lastvisit=?"
-	args := []interface{}{now}
-
-	if resetDay {
-		sqlStr += ", dayvisit=1"
-	} else {
-		sqlStr += ", dayvisit=dayvisit+1"
-	}
-
-	if resetWeek {
-		sqlStr += ", weekvisit=1"
-	} else {
-		sqlStr += ", weekvisit=weekvisit+1"
-	}
-
-	if resetMonth {
-		sqlStr += ", monthvisit=1"
-	} else {
-		sqlStr += ", monthvisit=monthvisit+1"
-	}
-
-	sqlStr += " where articleid=?"
-	args = append(args, id)
-
-	_, err = utils.Db.Exec(sqlStr, args...)
-
-	// 清理缓存
-	if err == nil && utils.IsRedisEnabled() {
-		InvalidateArticleCache(id)
-	}
-
-	return err
-}
-
 // GetAllArticlesForSitemap 获取所有文章用于生成 sitemap
 // 只返回必要的字段：ArticleID, LastUpdate
 func GetAllArticlesForSitemap() ([]*model.Article, error) {
@@ -1,6 +1,6 @@
 // cache_dao.go
 // 缓存 DAO
-// 封装了基于 Redis 的常用数据查询（小说、章节、分类等），提供自动缓存能力
+// 封装了常用数据查询（小说、章节、分类等），提供进程内 LRU + Redis 两级自动缓存能力
 package dao
 
 import (
@@ -8,6 +8,7 @@ import (
 	"bookweb/utils"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -45,57 +46,69 @@ func searchCountCacheKey(keyword string) string {
 	return fmt.Sprintf("search_count:%s", keyword)
 }
 
-// getCached 泛型缓存获取函数 (Redis)
-func getCached[T any](key string, ttl time.Duration, fetchFunc func() (T, error)) (T, error) {
-	// 1. Check Cache
-	cached, err := utils.CacheGet(key)
-	if err == nil && cached != "" {
-		var result T
-		if json.Unmarshal([]byte(cached), &result) == nil {
-			return result, nil
-		}
+// Cache tags: 缓存写入时登记依赖，数据变更时按标签清理所有相关缓存
+const (
+	HomeTag = "home" // 首页及全站最新列表
+	RankTag = "rank" // 排行、按点击 / 字段排序的列表
+)
+
+// ArticleTag 小说标签：小说信息、章节列表、章节元数据、上下章、信息页及目录页
+func ArticleTag(id int) string {
+	return fmt.Sprintf("article:%d", id)
+}
+
+// SortTag 分类标签：分类信息、分类小说列表及分类页
+func SortTag(id int) string {
+	return fmt.Sprintf("sort:%d", id)
+}
+
+// sortListTags 分类列表的标签，sortID 为 0 (全站) 时属于首页
+func sortListTags(sortID int, tags ...string) []string {
+	tags = append(tags, SortTag(sortID))
+	if sortID == 0 {
+		tags = append(tags, HomeTag)
 	}
+	return tags
+}
 
-	// 2. Fetch
-	result, err := fetchFunc()
+// getCached 泛型缓存获取函数 (L1 进程内缓存 + L2 Redis)
+// 同一键的并发回源只执行一次；过期后在重验证窗口内返回旧值并后台刷新；写入时将键登记到 tags 下
+func getCached[T any](key string, ttl time.Duration, fetchFunc func() (T, error), tags ...string) (T, error) {
+	var result T
+	cached, err := utils.DataLoader.Load(key, ttl, func() (string, error) {
+		value, err := fetchFunc()
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(value)
+		return string(data), err
+	}, tags...)
 	if err != nil {
-		var zero T
-		return zero, err
+		return result, err
 	}
-
-	// 3. Set Cache
-	if data, err := json.Marshal(result); err == nil {
-		utils.CacheSet(key, string(data), ttl)
+	if err := json.Unmarshal([]byte(cached), &result); err != nil {
+		// 缓存内容无法解析时直接回源
+		return fetchFunc()
 	}
-
 	return result, nil
 }
 
 // GetArticleByIDCached 带缓存的获取文章
 func GetArticleByIDCached(id int) (*model.Article, error) {
-	if !utils.IsRedisEnabled() {
-		return GetArticleByID(id)
-	}
 	return getCached(articleCacheKey(id), ArticleCacheTTL, func() (*model.Article, error) {
 		return GetArticleByID(id)
-	})
+	}, ArticleTag(id))
 }
 
 // GetChaptersByArticleIDCached 带缓存的获取章节列表
 func GetChaptersByArticleIDCached(articleID int) ([]*model.Chapter, error) {
-	if !utils.IsRedisEnabled() {
-		return GetChaptersByArticleID(articleID)
-	}
 	return getCached(chaptersCacheKey(articleID), ChaptersCacheTTL, func() ([]*model.Chapter, error) {
 		return GetChaptersByArticleID(articleID)
-	})
+	}, ArticleTag(articleID))
 }
 
 // GetAllSortsCached 带缓存的获取所有分类
 func GetAllSortsCached() ([]*model.Sort, error) {
-	if !utils.IsRedisEnabled() {
-		return GetAllSorts()
-	}
 	return getCached(sortsCacheKey(), SortsCacheTTL, func() ([]*model.Sort, error) {
 		return GetAllSorts()
 	})
@@ -103,34 +116,47 @@ func GetAllSortsCached() ([]*model.Sort, error) {
 
 // GetRankArticlesCached 带缓存的获取排行榜
 func GetRankArticlesCached(orderBy string, limit int) ([]*model.Article, error) {
-	if !utils.IsRedisEnabled() {
-		return GetRankArticles(orderBy, limit)
-	}
 	return getCached(rankCacheKey(orderBy, limit), RankCacheTTL, func() ([]*model.Article, error) {
 		return GetRankArticles(orderBy, limit)
-	})
+	}, RankTag)
 }
 
 // InvalidateArticleCache 使文章缓存失效
 func InvalidateArticleCache(id int) {
-	if utils.IsRedisEnabled() {
-		utils.CacheDel(articleCacheKey(id))
-		utils.CacheDel(chaptersCacheKey(id))
+	utils.DataCacheDel(articleCacheKey(id), chaptersCacheKey(id))
+}
+
+// InvalidateChapterCache 使章节缓存失效 (元数据及进程内内容缓存)
+func InvalidateChapterCache(articleID, chapterID int) {
+	utils.InvalidateChapterContent(articleID, chapterID)
+	utils.DataCacheDel(fmt.Sprintf("chapter_%d", chapterID))
+}
+
+// InvalidateArticleVisibility 小说或章节显示状态变更后清理缓存
+// 按小说标签清理小说信息、章节列表、各章节元数据、上下章及信息页 / 目录页整页缓存
+func InvalidateArticleVisibility(articleID int) {
+	InvalidateArticleCache(articleID)
+	utils.InvalidateCacheTags(ArticleTag(articleID))
+}
+
+// InvalidateArticleDependents 小说信息变更 (编辑、删除、显示状态) 后清理所有依赖该小说的缓存
+// 包括小说自身 (见 InvalidateArticleVisibility)、所属分类列表、首页及排行
+func InvalidateArticleDependents(articleID int, sortIDs ...int) {
+	InvalidateArticleCache(articleID)
+	tags := []string{ArticleTag(articleID), HomeTag, RankTag}
+	for _, sortID := range sortIDs {
+		tags = append(tags, SortTag(sortID))
 	}
+	utils.InvalidateCacheTags(tags...)
 }
 
 // InvalidateSortsCache 使分类缓存失效
 func InvalidateSortsCache() {
-	if utils.IsRedisEnabled() {
-		utils.CacheDel(sortsCacheKey())
-	}
+	utils.DataCacheDel(sortsCacheKey())
 }
 
 // SearchArticlesCached 带缓存的搜索文章
 func SearchArticlesCached(keyword string, offset, limit int) ([]*model.Article, error) {
-	if !utils.IsRedisEnabled() {
-		return SearchArticles(keyword, offset, limit)
-	}
 	return getCached(searchCacheKey(keyword, offset, limit), SearchCacheTTL, func() ([]*model.Article, error) {
 		return SearchArticles(keyword, offset, limit)
 	})
@@ -138,9 +164,6 @@ func SearchArticlesCached(keyword string, offset, limit int) ([]*model.Article,
 
 // GetSearchCountCached 带缓存的获取搜索结果总数
 func GetSearchCountCached(keyword string) (int, error) {
-	if !utils.IsRedisEnabled() {
-		return GetSearchCount(keyword)
-	}
 	return getCached(searchCountCacheKey(keyword), SearchCacheTTL, func() (int, error) {
 		return GetSearchCount(keyword)
 	})
@@ -152,10 +175,76 @@ func langtailCacheKey(sourceID int) string {
 
 // GetLangtailsBySourceIDCached 带缓存的长尾词获取
 func GetLangtailsBySourceIDCached(sourceID int) ([]*model.Langtail, error) {
-	if !utils.IsRedisEnabled() {
-		return GetLangtailsBySourceID(sourceID)
-	}
 	return getCached(langtailCacheKey(sourceID), 1*time.Hour, func() ([]*model.Langtail, error) {
 		return GetLangtailsBySourceID(sourceID)
 	})
 }
+
+// 缓存清理范围
+const (
+	CacheScopeAll    = "all"    // 本站全部缓存 (保留点击量缓冲)
+	CacheScopePage   = "page"   // 整页缓存
+	CacheScopeData   = "data"   // 数据查询缓存 (含搜索结果及章节内容)
+	CacheScopeSearch = "search" // 搜索结果数据及搜索页整页缓存
+)
+
+// ClearCache 按范围清理进程内及 Redis 缓存，返回删除的 Redis 键数量
+// 清理前先将点击量缓冲回写 MySQL，回写失败时不清理；只扫描本站前缀下的键，不影响共用同一 Redis 库的其它站点
+// 未配置 redis.prefix 时无法区分本站的键，拒绝清理全部 / 数据范围 (会扫描删除整个 Redis 库)
+func ClearCache(scope string) (int, error) {
+	if (scope == CacheScopeAll || scope == CacheScopeData) && utils.IsRedisEnabled() && utils.RedisPrefix() == "" {
+		return 0, fmt.Errorf("未配置 redis.prefix，清理全部 / 数据缓存会删除整个 Redis 库中的键，请先配置键前缀，或按整页 / 搜索范围清理")
+	}
+
+	if _, err := FlushVisitBuffers(); err != nil {
+		return 0, fmt.Errorf("回写点击量失败: %w", err)
+	}
+
+	isVisitBuffer := func(key string) bool {
+		return strings.HasPrefix(key, VisitBufferKeyPrefix)
+	}
+	searchPrefixes := []string{"search:", "search_count:"}
+
+	// 进程内缓存
+	switch scope {
+	case CacheScopeAll:
+		utils.ClearContentCache()
+		utils.ClearDataCache()
+	case CacheScopeData:
+		utils.ClearContentCache()
+		utils.ClearDataCache()
+	case CacheScopeSearch:
+		utils.DataCacheDelPrefix(searchPrefixes...)
+	case CacheScopePage:
+	default:
+		return 0, fmt.Errorf("未知的清理范围: %s", scope)
+	}
+
+	if !utils.IsRedisEnabled() {
+		return 0, nil
+	}
+
+	switch scope {
+	case CacheScopeAll:
+		return utils.CacheDelPattern("*", isVisitBuffer)
+	case CacheScopePage:
+		return utils.ClearPageCache()
+	case CacheScopeData:
+		return utils.CacheDelPattern("*", func(key string) bool {
+			return isVisitBuffer(key) ||
+				strings.HasPrefix(key, utils.PageCacheKeyPrefix) ||
+				strings.HasPrefix(key, utils.CacheTagKeyPrefix)
+		})
+	default:
+		total := 0
+		for _, prefix := range searchPrefixes {
+			n, err := utils.CacheDelPattern(prefix+"*", nil)
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+		n, err := utils.ClearPageCache("search")
+		return total + n, err
+	}
+}
@@ -7,19 +7,17 @@ import (
 	"bookweb/model"
 	"bookweb/utils"
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"strconv"
 	"time"
 )
 
-// GetChapterByID 根据ChapterID获取章节详情（包含内容）
+// GetChapterByID 根据ChapterID获取章节详情（包含内容），隐藏章节返回 sql.ErrNoRows
 func GetChapterByID(id int) (*model.Chapter, error) {
 	var row *sql.Row
 	if stmtGetChapterByID != nil {
 		row = stmtGetChapterByID.QueryRow(id)
 	} else {
-		sqlStr := "select chapterid, siteid, articleid, articlename, volumeid, posterid, poster, postdate, lastupdate, chaptername, chapterorder, size, saleprice, salenum, totalcost, attachment, isvip, chaptertype, power, display from jieqi_article_chapter where chapterid = ?"
+		sqlStr := "select chapterid, siteid, articleid, articlename, volumeid, posterid, poster, postdate, lastupdate, chaptername, chapterorder, size, saleprice, salenum, totalcost, attachment, isvip, chaptertype, power, display from jieqi_article_chapter where chapterid = ? and display = 0"
 		row = utils.Db.QueryRow(sqlStr, id)
 	}
 	ch := &model.Chapter{}
@@ -27,8 +25,8 @@ func GetChapterByID(id int) (*model.Chapter, error) {
 	if err != nil {
 		return nil, err
 	}
-	// Read content from file
-	content, err := utils.GetChapterFileContent(ch.ArticleID, ch.ChapterID, ch.ChapterOrder)
+	// Read content from file (LRU cached)
+	content, err := utils.GetChapterContentCached(ch.ArticleID, ch.ChapterID, ch.ChapterOrder)
 	if err == nil {
 		ch.Content = content
 	} else {
@@ -40,58 +38,42 @@ func GetChapterByID(id int) (*model.Chapter, error) {
 	return ch, nil
 }
 
-// GetChapterByIDCached 带缓存获取章节详情（不包含内容文本）
-// 缓存策略：仅缓存章节元数据，内容实时读取文件
+// GetChapterByIDCached 带缓存获取章节详情
+// 缓存策略：两级数据缓存仅缓存章节元数据，内容走进程内 LRU 缓存
 func GetChapterByIDCached(id int) (*model.Chapter, error) {
 	cacheKey := fmt.Sprintf("chapter_%d", id)
-
-	var ch *model.Chapter
-
-	// 尝试从缓存获取元数据
-	if cached, err := utils.CacheGet(cacheKey); err == nil && cached != "" {
-		ch = &model.Chapter{}
-		if err := json.Unmarshal([]byte(cached), ch); err != nil {
-			ch = nil // 解析失败，回源
-		}
-	}
-
-	// 回源数据库
-	if ch == nil {
-		var err error
-		ch, err = GetChapterByID(id)
+	ch, err := getCached(cacheKey, 1*time.Hour, func() (*model.Chapter, error) {
+		ch, err := GetChapterByID(id)
 		if err != nil {
 			return nil, err
 		}
-
-		// 缓存元数据 (先清空 Content 防止大文本存入 Redis)
-		realContent := ch.Content
+		// 所属小说在查询后才能确定，单独登记标签
+		utils.TagCacheKey(cacheKey, ArticleTag(ch.ArticleID))
+		// 清空 Content 防止大文本存入缓存
 		ch.Content = ""
-		if data, err := json.Marshal(ch); err == nil {
-			utils.CacheSet(cacheKey, string(data), 1*time.Hour)
-		}
-		ch.Content = realContent // 恢复内容以便返回
 		return ch, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// 缓存命中后，需要读取文件内容
-	content, err := utils.GetChapterFileContent(ch.ArticleID, ch.ChapterID, ch.ChapterOrder)
+	content, err := utils.GetChapterContentCached(ch.ArticleID, ch.ChapterID, ch.ChapterOrder)
 	if err == nil {
 		ch.Content = content
 	} else {
 		ch.Content = "章节内容缺失，请联系管理员修复。"
 	}
-
 	return ch, nil
 }
 
-// GetPrevChapterID 获取上一章节ID
+// GetPrevChapterID 获取上一章节ID (跳过分卷及隐藏章节)
 func GetPrevChapterID(articleID, currentOrder int) (int, error) {
 	var id int
 	var err error
 	if stmtGetPrevChapterID != nil {
 		err = stmtGetPrevChapterID.QueryRow(articleID, currentOrder).Scan(&id)
 	} else {
-		sqlStr := "select chapterid from jieqi_article_chapter where articleid = ? and chapterorder < ? order by chapterorder desc limit 1"
+		sqlStr := "select chapterid from jieqi_article_chapter where articleid = ? and chapterorder < ? and chaptertype = 0 and display = 0 order by chapterorder desc limit 1"
 		err = utils.Db.QueryRow(sqlStr, articleID, currentOrder).Scan(&id)
 	}
 	return id, err
@@ -99,42 +81,26 @@ func GetPrevChapterID(articleID, currentOrder int) (int, error) {
 
 // GetPrevChapterIDCached 带缓存获取上一章节ID
 func GetPrevChapterIDCached(articleID, currentOrder int) (int, error) {
-	cacheKey := fmt.Sprintf("chapter_prev_%d_%d", articleID, currentOrder)
-	if cached, err := utils.CacheGet(cacheKey); err == nil && cached != "" {
-		if id, err := strconv.Atoi(cached); err == nil {
-			return id, nil
-		}
-	}
-	id, err := GetPrevChapterID(articleID, currentOrder)
-	if err == nil {
-		utils.CacheSet(cacheKey, strconv.Itoa(id), 1*time.Hour)
-	}
-	return id, err
+	return getCached(fmt.Sprintf("chapter_prev_%d_%d", articleID, currentOrder), 1*time.Hour, func() (int, error) {
+		return GetPrevChapterID(articleID, currentOrder)
+	}, ArticleTag(articleID))
 }
 
 // GetNextChapterIDCached 带缓存获取下一章节ID
 func GetNextChapterIDCached(articleID, currentOrder int) (int, error) {
-	cacheKey := fmt.Sprintf("chapter_next_%d_%d", articleID, currentOrder)
-	if cached, err := utils.CacheGet(cacheKey); err == nil && cached != "" {
-		if id, err := strconv.Atoi(cached); err == nil {
-			return id, nil
-		}
-	}
-	id, err := GetNextChapterID(articleID, currentOrder)
-	if err == nil {
-		utils.CacheSet(cacheKey, strconv.Itoa(id), 1*time.Hour)
-	}
-	return id, err
+	return getCached(fmt.Sprintf("chapter_next_%d_%d", articleID, currentOrder), 1*time.Hour, func() (int, error) {
+		return GetNextChapterID(articleID, currentOrder)
+	}, ArticleTag(articleID))
 }
 
-// GetNextChapterID 获取下一章节ID
+// GetNextChapterID 获取下一章节ID (跳过分卷及隐藏章节)
 func GetNextChapterID(articleID, currentOrder int) (int, error) {
 	var id int
 	var err error
 	if stmtGetNextChapterID != nil {
 		err = stmtGetNextChapterID.QueryRow(articleID, currentOrder).Scan(&id)
 	} else {
-		sqlStr := "select chapterid from jieqi_article_chapter where articleid = ? and chapterorder > ? order by chapterorder asc limit 1"
+		sqlStr := "select chapterid from jieqi_article_chapter where articleid = ? and chapterorder > ? and chaptertype = 0 and display = 0 order by chapterorder asc limit 1"
 		err = utils.Db.QueryRow(sqlStr, articleID, currentOrder).Scan(&id)
 	}
 	return id, err
@@ -0,0 +1,80 @@
+// import_dao.go
+// 导入 DAO
+// 处理小说导入时的文章、章节写入及统计字段回填
+package dao
+
+import (
+	"bookweb/model"
+	"bookweb/utils"
+	"time"
+)
+
+// InsertArticle 新增小说 (display 取 a.Display)，返回 articleid
+func InsertArticle(a *model.Article) (int, error) {
+	now := time.Now().Unix()
+	sqlStr := `INSERT INTO jieqi_article_article
+		(postdate, lastupdate, articlename, author, sortid, intro, notice, setting, fullflag, display)
+		VALUES (?, ?, ?, ?, ?, ?, '', '', ?, ?)`
+	res, err := utils.Db.Exec(sqlStr, now, now, a.ArticleName, a.Author, a.SortID, a.Intro, a.FullFlag, a.Display)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// InsertChapter 新增章节 (或分卷)，返回 chapterid
+func InsertChapter(c *model.Chapter) (int, error) {
+	now := time.Now().Unix()
+	sqlStr := `INSERT INTO jieqi_article_chapter
+		(articleid, articlename, volumeid, postdate, lastupdate, chaptername, chapterorder, size, attachment, chaptertype)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, '', ?)`
+	res, err := utils.Db.Exec(sqlStr, c.ArticleID, c.ArticleName, c.VolumeID, now, now,
+		c.ChapterName, c.ChapterOrder, c.Size, c.ChapterType)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// RefreshArticleStats 根据章节表回填小说的章节数、字数、最新章节及最新分卷 (仅统计显示的章节)，并将 lastupdate 更新为当前时间
+func RefreshArticleStats(articleID int) error {
+	return refreshArticleStats(articleID, true)
+}
+
+// RecountArticleStats 同 RefreshArticleStats，但不修改 lastupdate (显示 / 隐藏章节等未新增内容的操作使用，避免影响最近更新排序)
+func RecountArticleStats(articleID int) error {
+	return refreshArticleStats(articleID, false)
+}
+
+// refreshArticleStats 回填小说统计，touch 为 true 时同时更新 lastupdate
+func refreshArticleStats(articleID int, touch bool) error {
+	var chapters, size int
+	err := utils.Db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(size), 0) FROM jieqi_article_chapter
+		WHERE articleid = ? AND chaptertype = 0 AND display = 0`, articleID).Scan(&chapters, &size)
+	if err != nil {
+		return err
+	}
+
+	var lastChapterID int
+	var lastChapter string
+	utils.Db.QueryRow(`SELECT chapterid, chaptername FROM jieqi_article_chapter
+		WHERE articleid = ? AND chaptertype = 0 AND display = 0 ORDER BY chapterorder DESC LIMIT 1`, articleID).Scan(&lastChapterID, &lastChapter)
+
+	var lastVolumeID int
+	var lastVolume string
+	utils.Db.QueryRow(`SELECT chapterid, chaptername FROM jieqi_article_chapter
+		WHERE articleid = ? AND chaptertype = 1 AND display = 0 ORDER BY chapterorder DESC LIMIT 1`, articleID).Scan(&lastVolumeID, &lastVolume)
+
+	sqlStr := "UPDATE jieqi_article_article SET chapters = ?, size = ?, lastchapterid = ?, lastchapter = ?, lastvolumeid = ?, lastvolume = ?"
+	args := []interface{}{chapters, size, lastChapterID, lastChapter, lastVolumeID, lastVolume}
+	if touch {
+		sqlStr += ", lastupdate = ?"
+		args = append(args, time.Now().Unix())
+	}
+	sqlStr += " WHERE articleid = ?"
+	args = append(args, articleID)
+	_, err = utils.Db.Exec(sqlStr, args...)
+	return err
+}
@@ -0,0 +1,87 @@
+// integrity_dao.go
+// 存储校验 DAO
+// 为章节文件完整性检查提供章节元数据查询及修复操作
+package dao
+
+import (
+	"bookweb/model"
+	"bookweb/utils"
+	"strings"
+)
+
+// GetChaptersForCheck 获取待校验的章节 (articleID 为 0 时获取全部)，按小说、章节顺序排序
+func GetChaptersForCheck(articleID int) ([]*model.Chapter, error) {
+	sqlStr := `SELECT chapterid, articleid, chaptername, chapterorder, size, chaptertype, display
+		FROM jieqi_article_chapter`
+	var args []interface{}
+	if articleID > 0 {
+		sqlStr += " WHERE articleid = ?"
+		args = append(args, articleID)
+	}
+	sqlStr += " ORDER BY articleid ASC, chapterorder ASC"
+
+	rows, err := utils.Db.Query(sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chapters []*model.Chapter
+	for rows.Next() {
+		c := &model.Chapter{}
+		if err := rows.Scan(&c.ChapterID, &c.ArticleID, &c.ChapterName, &c.ChapterOrder,
+			&c.Size, &c.ChapterType, &c.Display); err != nil {
+			return nil, err
+		}
+		chapters = append(chapters, c)
+	}
+	return chapters, rows.Err()
+}
+
+// GetArticleIDsForCheck 获取小说 ID 列表 (含隐藏小说，articleID 为 0 时获取全部)
+func GetArticleIDsForCheck(articleID int) ([]int, error) {
+	sqlStr := "SELECT articleid FROM jieqi_article_article"
+	var args []interface{}
+	if articleID > 0 {
+		sqlStr += " WHERE articleid = ?"
+		args = append(args, articleID)
+	}
+	sqlStr += " ORDER BY articleid ASC"
+
+	rows, err := utils.Db.Query(sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// hideChaptersBatchSize 隐藏章节时每条 UPDATE 的章节数，避免 IN 列表过长
+const hideChaptersBatchSize = 500
+
+// HideChapters 批量隐藏章节 (display = 1)，按 hideChaptersBatchSize 分批执行，返回已隐藏的章节数
+func HideChapters(ids []int) (int, error) {
+	hidden := 0
+	for start := 0; start < len(ids); start += hideChaptersBatchSize {
+		batch := ids[start:min(start+hideChaptersBatchSize, len(ids))]
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		if _, err := utils.Db.Exec("UPDATE jieqi_article_chapter SET display = 1 WHERE chapterid IN ("+placeholders+")", args...); err != nil {
+			return hidden, err
+		}
+		hidden += len(batch)
+	}
+	return hidden, nil
+}
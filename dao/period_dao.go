@@ -0,0 +1,118 @@
+// period_dao.go
+// 周期计数清零
+// 在站点时区的日/周/月开始时将小说的日/周/月点击量及推荐票清零，多实例部署时通过 period_reset 表保证每个周期只执行一次
+package dao
+
+import (
+	"bookweb/utils"
+	"fmt"
+	"time"
+)
+
+// periodRolloverInterval 检查周期切换的间隔
+const periodRolloverInterval = time.Minute
+
+// countPeriod 计数周期
+type countPeriod struct {
+	name    string                   // 周期名，同时作为 period_reset 记录的前缀
+	columns []string                 // 需要清零的字段 (点击量在前、推荐票在后)
+	key     func(t time.Time) string // 周期标识
+	start   func(t time.Time) int64  // 周期开始时间
+}
+
+// countPeriods 按月、周、日的顺序检查
+var countPeriods = []countPeriod{
+	{
+		name:    "month",
+		columns: []string{"monthvisit", "monthvote"},
+		key:     func(t time.Time) string { return t.Format("2006-01") },
+		start:   utils.MonthStart,
+	},
+	{
+		name:    "week",
+		columns: []string{"weekvisit", "weekvote"},
+		key: func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		},
+		start: utils.WeekStart,
+	},
+	{
+		name:    "day",
+		columns: []string{"dayvisit", "dayvote"},
+		key:     func(t time.Time) string { return t.Format("2006-01-02") },
+		start:   utils.DayStart,
+	},
+}
+
+// StartPeriodRollover 启动周期清零任务，启动时立即检查一次，之后每分钟检查
+func StartPeriodRollover() {
+	go func() {
+		RollPeriodCounters(utils.SiteNow())
+		ticker := time.NewTicker(periodRolloverInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			RollPeriodCounters(utils.SiteNow())
+		}
+	}()
+}
+
+// RollPeriodCounters 检查各周期是否已切换，未清零的周期执行清零
+func RollPeriodCounters(now time.Time) {
+	for _, p := range countPeriods {
+		if err := rollPeriod(p, now); err != nil {
+			utils.LogError("Period", "Reset %s counters failed: %v", p.name, err)
+		}
+	}
+}
+
+// rollPeriod 抢占本周期的清零记录并清零计数
+// 记录插入成功的实例负责清零，其它实例及后续检查因主键冲突直接跳过；清零失败时删除记录，下次检查重试
+func rollPeriod(p countPeriod, now time.Time) error {
+	period := p.name + ":" + p.key(now)
+
+	var previous int
+	if err := utils.Db.QueryRow("select count(*) from period_reset where period like ?", p.name+":%").Scan(&previous); err != nil {
+		return err
+	}
+
+	res, err := utils.Db.Exec("insert ignore into period_reset (period, resettime) values (?, ?)", period, now.Unix())
+	if err != nil {
+		return err
+	}
+	if claimed, err := res.RowsAffected(); err != nil || claimed == 0 {
+		return err
+	}
+
+	// 清零前回写本实例内存及 Redis 缓冲中的点击量，使其计入上一周期
+	// 其它实例内存中尚未推送到 Redis 的点击 (最多一个 visit.flush_interval) 仍会在清零后回写，计入新周期
+	if _, err := FlushVisitBuffers(); err != nil {
+		utils.LogWarn("Period", "Flush visits before %s reset failed: %v", period, err)
+	}
+
+	visitCol, voteCol := p.columns[0], p.columns[1]
+	var sqlStr string
+	var args []interface{}
+	if previous == 0 {
+		// 首次运行时无法确定上次清零时间，只清零本周期内未被点击/投票的小说，避免清空本周期已有的计数
+		start := p.start(now)
+		sqlStr = fmt.Sprintf("update jieqi_article_article set %[1]s=case when lastvisit<? then 0 else %[1]s end, %[2]s=case when lastvote<? then 0 else %[2]s end where (%[1]s<>0 and lastvisit<?) or (%[2]s<>0 and lastvote<?)", visitCol, voteCol)
+		args = []interface{}{start, start, start, start}
+	} else {
+		sqlStr = fmt.Sprintf("update jieqi_article_article set %[1]s=0, %[2]s=0 where %[1]s<>0 or %[2]s<>0", visitCol, voteCol)
+	}
+
+	res, err = utils.Db.Exec(sqlStr, args...)
+	if err != nil {
+		utils.Db.Exec("delete from period_reset where period = ?", period)
+		return err
+	}
+	rows, _ := res.RowsAffected()
+
+	// 只保留每类周期的最新记录
+	utils.Db.Exec("delete from period_reset where period like ? and period <> ?", p.name+":%", period)
+
+	utils.InvalidateCacheTags(RankTag, HomeTag)
+	utils.LogInfo("Period", "Reset %s counters for %s, %d articles updated", p.name, period, rows)
+	return nil
+}
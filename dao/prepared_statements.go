@@ -35,17 +35,17 @@ var (
 
 // SQL语句常量
 const (
-	sqlGetArticleByID = `SELECT articleid, siteid, postdate, lastupdate, articlename, keywords, initial, authorid, author, posterid, poster, agentid, agent, sortid, typeid, intro, notice, setting, lastvolumeid, lastvolume, lastchapterid, lastchapter, chapters, size, lastvisit, dayvisit, weekvisit, monthvisit, allvisit, lastvote, dayvote, weekvote, monthvote, allvote, fullflag, imgflag FROM jieqi_article_article WHERE articleid = ?`
+	sqlGetArticleByID = `SELECT articleid, siteid, postdate, lastupdate, articlename, keywords, initial, authorid, author, posterid, poster, agentid, agent, sortid, typeid, intro, notice, setting, lastvolumeid, lastvolume, lastchapterid, lastchapter, chapters, size, lastvisit, dayvisit, weekvisit, monthvisit, allvisit, lastvote, dayvote, weekvote, monthvote, allvote, fullflag, imgflag FROM jieqi_article_article WHERE articleid = ? AND display = 0`
 
-	sqlGetChaptersByArticle = `SELECT chapterid, chaptername, chapterorder, isvip, size, lastupdate FROM jieqi_article_chapter WHERE articleid = ? ORDER BY chapterorder ASC`
+	sqlGetChaptersByArticle = `SELECT chapterid, volumeid, chaptername, chapterorder, isvip, saleprice, size, lastupdate, chaptertype FROM jieqi_article_chapter WHERE articleid = ? AND display = 0 ORDER BY chapterorder ASC`
 
-	sqlGetVisitArticles = `SELECT articleid, articlename, author, intro, size, lastupdate, sortid, fullflag, imgflag, lastchapterid, lastchapter FROM jieqi_article_article ORDER BY allvisit DESC LIMIT ?`
+	sqlGetVisitArticles = `SELECT articleid, articlename, author, intro, size, lastupdate, sortid, fullflag, imgflag, lastchapterid, lastchapter FROM jieqi_article_article WHERE display = 0 ORDER BY allvisit DESC LIMIT ?`
 
-	sqlGetChapterByID = `SELECT chapterid, siteid, articleid, articlename, volumeid, posterid, poster, postdate, lastupdate, chaptername, chapterorder, size, saleprice, salenum, totalcost, attachment, isvip, chaptertype, power, display FROM jieqi_article_chapter WHERE chapterid = ?`
+	sqlGetChapterByID = `SELECT chapterid, siteid, articleid, articlename, volumeid, posterid, poster, postdate, lastupdate, chaptername, chapterorder, size, saleprice, salenum, totalcost, attachment, isvip, chaptertype, power, display FROM jieqi_article_chapter WHERE chapterid = ? AND display = 0`
 
-	sqlGetPrevChapterID = `SELECT chapterid FROM jieqi_article_chapter WHERE articleid = ? AND chapterorder < ? ORDER BY chapterorder DESC LIMIT 1`
+	sqlGetPrevChapterID = `SELECT chapterid FROM jieqi_article_chapter WHERE articleid = ? AND chapterorder < ? AND chaptertype = 0 AND display = 0 ORDER BY chapterorder DESC LIMIT 1`
 
-	sqlGetNextChapterID = `SELECT chapterid FROM jieqi_article_chapter WHERE articleid = ? AND chapterorder > ? ORDER BY chapterorder ASC LIMIT 1`
+	sqlGetNextChapterID = `SELECT chapterid FROM jieqi_article_chapter WHERE articleid = ? AND chapterorder > ? AND chaptertype = 0 AND display = 0 ORDER BY chapterorder ASC LIMIT 1`
 
 	sqlGetAllSorts = `SELECT sortid, weight, caption, shortname FROM sort ORDER BY weight ASC`
 
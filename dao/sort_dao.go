@@ -7,7 +7,6 @@ import (
 	"bookweb/model"
 	"bookweb/utils"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -65,26 +64,7 @@ func UpdateSort(id int, caption, shortName string, weight int) error {
 
 // GetSortByIDCached 带缓存获取单个分类信息
 func GetSortByIDCached(sortID int) (*model.Sort, error) {
-	cacheKey := fmt.Sprintf("sort_%d", sortID)
-
-	// 尝试从缓存获取
-	if cached, err := utils.CacheGet(cacheKey); err == nil && cached != "" {
-		s := &model.Sort{}
-		if err := json.Unmarshal([]byte(cached), s); err == nil {
-			return s, nil
-		}
-	}
-
-	// 从数据库获取
-	s, err := GetSortByID(sortID)
-	if err != nil {
-		return nil, err
-	}
-
-	// 写入缓存（1小时过期）
-	if data, err := json.Marshal(s); err == nil {
-		utils.CacheSet(cacheKey, string(data), 1*time.Hour)
-	}
-
-	return s, nil
+	return getCached(fmt.Sprintf("sort_%d", sortID), 1*time.Hour, func() (*model.Sort, error) {
+		return GetSortByID(sortID)
+	}, SortTag(sortID))
 }
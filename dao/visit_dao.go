@@ -0,0 +1,400 @@
+// visit_dao.go
+// 点击量聚合
+// 信息页点击先在内存中按小说累加，定时以多行 UPDATE 批量回写 MySQL；多实例部署可开启 Redis 缓冲，由各实例汇总后统一回写
+// 按 visit.mode 区分总点击量与日/周/月点击量分别计入全部点击 (PV) 还是去重访客 (UV)
+package dao
+
+import (
+	"bookweb/config"
+	"bookweb/utils"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VisitBufferKeyPrefix Redis 点击量缓冲键前缀，清理缓存时需保留
+const VisitBufferKeyPrefix = "article:visit_buffer:"
+
+// 回写默认值
+const (
+	defaultVisitFlushInterval = 30 * time.Second
+	defaultVisitBatchSize     = 500
+	maxVisitBatchSize         = 5000 // 每本小说占用 9 个占位符，避免超过 MySQL 65535 个占位符的上限
+)
+
+// 点击量统计方式
+const (
+	VisitModePV   = "pv"   // 每次点击都计数
+	VisitModeUV   = "uv"   // 只计去重后的访客
+	VisitModeBoth = "both" // 总点击量计 PV，日/周/月点击量 (周期排行) 计 UV
+)
+
+// visitDelta 单本小说待回写的点击数
+type visitDelta struct {
+	All    int // 计入 allvisit
+	Period int // 计入 dayvisit/weekvisit/monthvisit
+}
+
+// visitBufferKey 小说总点击量缓冲键，日/周/月点击量缓冲键追加 ":period"
+func visitBufferKey(id int, period bool) string {
+	key := VisitBufferKeyPrefix + strconv.Itoa(id)
+	if period {
+		key += ":period"
+	}
+	return key
+}
+
+// VisitStats 点击量回写统计
+type VisitStats struct {
+	Pending       int           // 内存中待回写的小说数
+	PendingVisits int           // 内存中待回写的总点击数
+	Flushes       int64         // 回写次数
+	Articles      int64         // 累计回写的小说数 (按次累加)
+	Visits        int64         // 累计回写的总点击数
+	Failures      int64         // 回写失败次数
+	LastFlush     time.Time     // 最近一次回写时间
+	LastDuration  time.Duration // 最近一次回写耗时
+	LastError     string        // 最近一次回写错误
+}
+
+var (
+	visitMu      sync.Mutex
+	visitPending = make(map[int]visitDelta)
+	visitStats   VisitStats
+
+	// visitFlushMu 串行化回写，避免定时回写与清理缓存、停机回写并发执行
+	visitFlushMu sync.Mutex
+
+	visitStop chan struct{}
+	visitDone chan struct{}
+)
+
+// IncArticleVisit 记录一次小说点击，unique 表示该访客在去重窗口内首次访问
+// 按 visit.mode 决定计入哪些点击量，只累加内存计数，由定时任务批量回写
+func IncArticleVisit(id int, unique bool) {
+	if id <= 0 {
+		return
+	}
+	var d visitDelta
+	switch config.GetGlobalConfig().Visit.Mode {
+	case VisitModeUV:
+		if !unique {
+			return
+		}
+		d = visitDelta{All: 1, Period: 1}
+	case VisitModeBoth:
+		d.All = 1
+		if unique {
+			d.Period = 1
+		}
+	default:
+		d = visitDelta{All: 1, Period: 1}
+	}
+
+	visitMu.Lock()
+	p := visitPending[id]
+	p.All += d.All
+	p.Period += d.Period
+	visitPending[id] = p
+	visitMu.Unlock()
+}
+
+// takePendingVisits 取出并清空内存中的点击计数
+func takePendingVisits() map[int]visitDelta {
+	visitMu.Lock()
+	defer visitMu.Unlock()
+	if len(visitPending) == 0 {
+		return nil
+	}
+	deltas := visitPending
+	visitPending = make(map[int]visitDelta, len(deltas))
+	return deltas
+}
+
+// restorePendingVisits 回写失败时将点击计数加回内存，等待下次回写
+func restorePendingVisits(deltas map[int]visitDelta) {
+	visitMu.Lock()
+	defer visitMu.Unlock()
+	for id, delta := range deltas {
+		p := visitPending[id]
+		p.All += delta.All
+		p.Period += delta.Period
+		visitPending[id] = p
+	}
+}
+
+// GetVisitStats 获取点击量回写统计
+func GetVisitStats() VisitStats {
+	visitMu.Lock()
+	defer visitMu.Unlock()
+	stats := visitStats
+	stats.Pending = len(visitPending)
+	for _, delta := range visitPending {
+		stats.PendingVisits += delta.All
+	}
+	return stats
+}
+
+// StartVisitFlusher 启动点击量定时回写，间隔为 visit.flush_interval (秒)
+func StartVisitFlusher() {
+	interval := defaultVisitFlushInterval
+	if seconds := config.GetGlobalConfig().Visit.FlushInterval; seconds > 0 {
+		interval = time.Duration(seconds) * time.Second
+	}
+
+	visitStop = make(chan struct{})
+	visitDone = make(chan struct{})
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := FlushVisits(); err != nil {
+					utils.LogError("Visit", "Flush visits failed: %v", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}(visitStop, visitDone)
+	utils.LogInfo("Visit", "Visit flusher started, interval=%s", interval)
+}
+
+// StopVisitFlusher 停止定时回写并将剩余点击量全部写入 MySQL，停机时调用
+func StopVisitFlusher() {
+	if visitStop != nil {
+		close(visitStop)
+		<-visitDone
+		visitStop = nil
+	}
+	n, err := FlushVisitBuffers()
+	if err != nil {
+		utils.LogError("Visit", "Final visit flush failed: %v", err)
+		return
+	}
+	utils.LogInfo("Visit", "Final visit flush completed, %d articles updated", n)
+}
+
+// FlushVisits 回写一次点击量，返回更新的小说数
+// 开启 Redis 缓冲时先将本实例计数累加到 Redis，再汇总所有实例的缓冲统一回写
+func FlushVisits() (int, error) {
+	return flushVisits(false)
+}
+
+// FlushVisitBuffers 将内存及 Redis 中的全部点击量回写 MySQL，返回更新的小说数
+// 清理缓存及停机前调用；未开启 Redis 缓冲时也会回写 Redis 中遗留的缓冲
+func FlushVisitBuffers() (int, error) {
+	return flushVisits(true)
+}
+
+// flushVisits 回写点击量，drainRedis 为 true 时无论是否开启 Redis 缓冲都汇总 Redis 中的缓冲
+func flushVisits(drainRedis bool) (int, error) {
+	visitFlushMu.Lock()
+	defer visitFlushMu.Unlock()
+
+	start := time.Now()
+	deltas := takePendingVisits()
+
+	useRedis := config.GetGlobalConfig().Visit.RedisBuffer && utils.IsRedisEnabled()
+	if useRedis {
+		if err := pushVisitBuffers(deltas); err != nil {
+			// Redis 不可用时直接写库
+			utils.LogWarn("Visit", "Push visit buffers to Redis failed, writing to MySQL: %v", err)
+			useRedis = false
+		}
+	}
+
+	var (
+		n, visits int
+		err       error
+	)
+	if len(deltas) > 0 {
+		var failed map[int]visitDelta
+		n, visits, failed, err = addArticleVisitsBatch(deltas)
+		restorePendingVisits(failed)
+	}
+	if (useRedis || drainRedis) && utils.IsRedisEnabled() {
+		rn, rv, rerr := drainVisitBuffers()
+		n += rn
+		visits += rv
+		if err == nil {
+			err = rerr
+		}
+	}
+
+	if len(deltas) > 0 || n > 0 || err != nil {
+		recordVisitFlush(n, visits, time.Since(start), err)
+	}
+	return n, err
+}
+
+// recordVisitFlush 记录回写统计
+func recordVisitFlush(articles, visits int, duration time.Duration, err error) {
+	visitMu.Lock()
+	defer visitMu.Unlock()
+	visitStats.Flushes++
+	visitStats.Articles += int64(articles)
+	visitStats.Visits += int64(visits)
+	visitStats.LastFlush = time.Now()
+	visitStats.LastDuration = duration
+	visitStats.LastError = ""
+	if err != nil {
+		visitStats.Failures++
+		visitStats.LastError = err.Error()
+	}
+}
+
+// pushVisitBuffers 将本实例的点击计数累加到 Redis 缓冲，已写入的计数从 deltas 中移除
+// 出错时 deltas 中保留尚未写入 Redis 的部分，由调用方直接写库
+func pushVisitBuffers(deltas map[int]visitDelta) error {
+	for id, d := range deltas {
+		if d.All > 0 {
+			if _, err := utils.CacheIncrBy(visitBufferKey(id, false), int64(d.All)); err != nil {
+				return err
+			}
+			d.All = 0
+			deltas[id] = d
+		}
+		if d.Period > 0 {
+			if _, err := utils.CacheIncrBy(visitBufferKey(id, true), int64(d.Period)); err != nil {
+				return err
+			}
+		}
+		delete(deltas, id)
+	}
+	return nil
+}
+
+// drainVisitBuffers 扫描 Redis 点击量缓冲，原子读取并删除后批量回写，失败时加回缓冲
+// 读取即删除，缓冲键只在有新点击时存在，不会随小说数量累积
+func drainVisitBuffers() (int, int, error) {
+	deltas := make(map[int]visitDelta)
+	err := utils.CacheScan(VisitBufferKeyPrefix+"*", func(key string) error {
+		idStr, period := strings.CutSuffix(strings.TrimPrefix(key, VisitBufferKeyPrefix), ":period")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return nil
+		}
+		oldValStr, err := utils.CacheGetDel(key)
+		if err != nil {
+			return err
+		}
+		if delta, _ := strconv.Atoi(oldValStr); delta > 0 {
+			d := deltas[id]
+			if period {
+				d.Period += delta
+			} else {
+				d.All += delta
+			}
+			deltas[id] = d
+		}
+		return nil
+	})
+
+	n, visits, failed, werr := addArticleVisitsBatch(deltas)
+	for id, d := range failed {
+		// 回写失败时将点击量加回缓冲区，避免丢失
+		if d.All > 0 {
+			utils.CacheIncrBy(visitBufferKey(id, false), int64(d.All))
+		}
+		if d.Period > 0 {
+			utils.CacheIncrBy(visitBufferKey(id, true), int64(d.Period))
+		}
+	}
+	if err == nil {
+		err = werr
+	}
+	return n, visits, err
+}
+
+// addArticleVisitsBatch 按 visit.batch_size 分批回写点击量，返回更新的小说数、点击数及写入失败的计数
+func addArticleVisitsBatch(deltas map[int]visitDelta) (int, int, map[int]visitDelta, error) {
+	ids := make([]int, 0, len(deltas))
+	for id, delta := range deltas {
+		if delta.All > 0 || delta.Period > 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, 0, nil, nil
+	}
+	// 按 ID 排序，多实例同时回写时保持相同的加锁顺序
+	sort.Ints(ids)
+
+	batchSize := config.GetGlobalConfig().Visit.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultVisitBatchSize
+	}
+	batchSize = min(batchSize, maxVisitBatchSize)
+
+	var (
+		n, visits int
+		failed    map[int]visitDelta
+		lastErr   error
+	)
+	now := utils.NowTime()
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:min(start+batchSize, len(ids))]
+		sqlStr, args := buildVisitUpdate(batch, deltas, now)
+		if _, err := utils.Db.Exec(sqlStr, args...); err != nil {
+			lastErr = err
+			if failed == nil {
+				failed = make(map[int]visitDelta)
+			}
+			for _, id := range batch {
+				failed[id] = deltas[id]
+			}
+			continue
+		}
+		n += len(batch)
+		for _, id := range batch {
+			visits += deltas[id].All
+		}
+	}
+	if lastErr != nil {
+		lastErr = fmt.Errorf("回写 %d 本小说点击量失败: %w", len(failed), lastErr)
+	}
+	return n, visits, failed, lastErr
+}
+
+// buildVisitUpdate 生成多行点击量更新语句
+// 日/周/月点击量由 StartPeriodRollover 在周期开始时统一清零，这里只做累加
+// 点击量不清理页面缓存，随缓存过期刷新，避免每次回写使大量信息页缓存失效
+func buildVisitUpdate(ids []int, deltas map[int]visitDelta, now int64) (string, []interface{}) {
+	var cases strings.Builder
+	cases.WriteString("case articleid")
+	for range ids {
+		cases.WriteString(" when ? then ?")
+	}
+	cases.WriteString(" end")
+	caseSQL := cases.String()
+
+	allArgs := make([]interface{}, 0, len(ids)*2)
+	periodArgs := make([]interface{}, 0, len(ids)*2)
+	for _, id := range ids {
+		allArgs = append(allArgs, id, deltas[id].All)
+		periodArgs = append(periodArgs, id, deltas[id].Period)
+	}
+
+	sqlStr := "update jieqi_article_article set allvisit=allvisit+" + caseSQL +
+		", dayvisit=dayvisit+" + caseSQL +
+		", weekvisit=weekvisit+" + caseSQL +
+		", monthvisit=monthvisit+" + caseSQL +
+		", lastvisit=? where articleid in (" + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + ")"
+
+	args := make([]interface{}, 0, len(allArgs)*4+1+len(ids))
+	args = append(args, allArgs...)
+	for i := 0; i < 3; i++ {
+		args = append(args, periodArgs...)
+	}
+	args = append(args, now)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return sqlStr, args
+}
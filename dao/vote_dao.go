@@ -0,0 +1,54 @@
+// vote_dao.go
+// 推荐票 DAO
+// 处理用户推荐票记录及小说推荐票计数的数据库操作
+package dao
+
+import (
+	"bookweb/utils"
+	"errors"
+)
+
+// ErrVoteLimit 今日推荐票已用完
+var ErrVoteLimit = errors.New("今日推荐票已用完")
+
+// AddArticleVote 用户为小说投推荐票，每日最多 allowance 票，返回当日剩余票数
+// 投票记录在事务中锁定用户行后写入，防止并发请求超出每日票数；小说表为 MyISAM，推荐票计数在事务提交后更新
+// 日/周/月推荐票由 StartPeriodRollover 在周期开始时统一清零，这里只做累加
+func AddArticleVote(userID, articleID, allowance, voteDay int) (int, error) {
+	tx, err := utils.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	if err := tx.QueryRow("SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Scan(&id); err != nil {
+		return 0, err
+	}
+	var used int
+	if err := tx.QueryRow("SELECT IFNULL(SUM(votenum), 0) FROM user_vote WHERE userid = ? AND voteday = ?",
+		userID, voteDay).Scan(&used); err != nil {
+		return 0, err
+	}
+	if used >= allowance {
+		return 0, ErrVoteLimit
+	}
+
+	now := utils.NowTime()
+	if _, err := tx.Exec("INSERT INTO user_vote (userid, articleid, votenum, voteday, votetime) VALUES (?, ?, 1, ?, ?)",
+		userID, articleID, voteDay, now); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if _, err := utils.Db.Exec(`UPDATE jieqi_article_article SET allvote = allvote + 1, dayvote = dayvote + 1,
+		weekvote = weekvote + 1, monthvote = monthvote + 1, lastvote = ? WHERE articleid = ?`, now, articleID); err != nil {
+		utils.LogError("DAO", "Update article %d vote stats failed: %v", articleID, err)
+	}
+	// 推荐票显示在信息页，需同时清理整页缓存
+	InvalidateArticleCache(articleID)
+	utils.InvalidateCacheTags(ArticleTag(articleID))
+	return allowance - used - 1, nil
+}
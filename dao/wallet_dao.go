@@ -0,0 +1,188 @@
+// wallet_dao.go
+// 钱包 DAO
+// 处理用户钱包余额、钱包流水及 VIP 章节购买记录的数据库操作
+package dao
+
+import (
+	"bookweb/model"
+	"bookweb/utils"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	// ErrInsufficientBalance 余额不足
+	ErrInsufficientBalance = errors.New("余额不足")
+	// ErrAlreadyPurchased 章节已购买
+	ErrAlreadyPurchased = errors.New("章节已购买")
+	// ErrChapterNotFound 章节不存在或已隐藏
+	ErrChapterNotFound = errors.New("章节不存在")
+	// ErrChapterNotForSale 章节无需购买
+	ErrChapterNotForSale = errors.New("该章节无需购买")
+)
+
+// mysqlErrDupEntry MySQL 唯一索引冲突错误码
+const mysqlErrDupEntry = 1062
+
+// GetWalletBalance 获取用户余额，未开通钱包时返回 0
+func GetWalletBalance(userID int) (int, error) {
+	var balance int
+	err := utils.Db.QueryRow("SELECT balance FROM user_wallet WHERE userid = ?", userID).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return balance, err
+}
+
+// HasPurchasedChapter 检查用户是否已购买章节
+func HasPurchasedChapter(userID, chapterID int) (bool, error) {
+	var count int
+	err := utils.Db.QueryRow("SELECT COUNT(*) FROM chapter_purchase WHERE userid = ? AND chapterid = ?",
+		userID, chapterID).Scan(&count)
+	return count > 0, err
+}
+
+// PurchaseChapter 购买章节：扣减余额、写入购买记录及流水，返回购买的章节及购买后余额
+// 章节价格在事务内直接读取数据库 (不经缓存)，避免按已过期的价格扣费；
+// 钱包相关表在同一事务中完成；章节表为 MyISAM，销量统计在事务提交后更新
+func PurchaseChapter(userID, chapterID int) (*model.Chapter, int, error) {
+	tx, err := utils.Db.Begin()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	ch := &model.Chapter{}
+	err = tx.QueryRow(`SELECT chapterid, articleid, articlename, chaptername, isvip, saleprice, chaptertype
+		FROM jieqi_article_chapter WHERE chapterid = ? AND display = 0`, chapterID).Scan(
+		&ch.ChapterID, &ch.ArticleID, &ch.ArticleName, &ch.ChapterName, &ch.IsVIP, &ch.SalePrice, &ch.ChapterType)
+	if err == sql.ErrNoRows || (err == nil && ch.ChapterType == 1) {
+		return nil, 0, ErrChapterNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	// 与 service.IsPaidChapter 一致：isvip=1 且 saleprice 大于 0
+	if ch.IsVIP != 1 || ch.SalePrice <= 0 {
+		return ch, 0, ErrChapterNotForSale
+	}
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM chapter_purchase WHERE userid = ? AND chapterid = ?",
+		userID, ch.ChapterID).Scan(&count); err != nil {
+		return ch, 0, err
+	}
+	if count > 0 {
+		return ch, 0, ErrAlreadyPurchased
+	}
+
+	var balance int
+	err = tx.QueryRow("SELECT balance FROM user_wallet WHERE userid = ? FOR UPDATE", userID).Scan(&balance)
+	if err != nil && err != sql.ErrNoRows {
+		return ch, 0, err
+	}
+	if balance < ch.SalePrice {
+		return ch, balance, ErrInsufficientBalance
+	}
+	balance -= ch.SalePrice
+	now := time.Now().Unix()
+
+	if _, err := tx.Exec("UPDATE user_wallet SET balance = ?, updatetime = ? WHERE userid = ?",
+		balance, now, userID); err != nil {
+		return ch, 0, err
+	}
+	// 唯一索引 (userid, chapterid) 防止并发重复购买
+	if _, err := tx.Exec("INSERT INTO chapter_purchase (userid, articleid, chapterid, price, buytime) VALUES (?, ?, ?, ?, ?)",
+		userID, ch.ArticleID, ch.ChapterID, ch.SalePrice, now); err != nil {
+		var myErr *mysql.MySQLError
+		if errors.As(err, &myErr) && myErr.Number == mysqlErrDupEntry {
+			return ch, 0, ErrAlreadyPurchased
+		}
+		return ch, 0, err
+	}
+	if _, err := tx.Exec(`INSERT INTO user_wallet_log (userid, amount, balance, logtype, articleid, chapterid, remark, addtime)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, userID, -ch.SalePrice, balance, model.WalletLogPurchase,
+		ch.ArticleID, ch.ChapterID, ch.ArticleName+" "+ch.ChapterName, now); err != nil {
+		return ch, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return ch, 0, err
+	}
+
+	if _, err := utils.Db.Exec("UPDATE jieqi_article_chapter SET salenum = salenum + 1, totalcost = totalcost + ? WHERE chapterid = ?",
+		ch.SalePrice, ch.ChapterID); err != nil {
+		utils.LogError("DAO", "Update chapter %d sale stats failed: %v", ch.ChapterID, err)
+	}
+	return ch, balance, nil
+}
+
+// TopUpWallet 调整用户余额 (amount 为负数时扣减，余额不可为负)，返回调整后余额
+func TopUpWallet(userID, amount int, remark string) (int, error) {
+	tx, err := utils.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	if _, err := tx.Exec("INSERT IGNORE INTO user_wallet (userid, balance, updatetime) VALUES (?, 0, ?)", userID, now); err != nil {
+		return 0, err
+	}
+	var balance int
+	if err := tx.QueryRow("SELECT balance FROM user_wallet WHERE userid = ? FOR UPDATE", userID).Scan(&balance); err != nil {
+		return 0, err
+	}
+	if balance+amount < 0 {
+		return balance, ErrInsufficientBalance
+	}
+	balance += amount
+
+	if _, err := tx.Exec("UPDATE user_wallet SET balance = ?, updatetime = ? WHERE userid = ?",
+		balance, now, userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`INSERT INTO user_wallet_log (userid, amount, balance, logtype, remark, addtime)
+		VALUES (?, ?, ?, ?, ?, ?)`, userID, amount, balance, model.WalletLogTopup, remark, now); err != nil {
+		return 0, err
+	}
+	return balance, tx.Commit()
+}
+
+// GetWalletLogs 分页获取钱包流水 (userID 为 0 时获取全部)，返回列表及总数
+func GetWalletLogs(userID, page, pageSize int) ([]*model.WalletLog, int, error) {
+	where := ""
+	var args []interface{}
+	if userID > 0 {
+		where = " WHERE l.userid = ?"
+		args = append(args, userID)
+	}
+
+	var total int
+	if err := utils.Db.QueryRow("SELECT COUNT(*) FROM user_wallet_log l"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	sqlStr := `SELECT l.logid, l.userid, IFNULL(u.username, ''), l.amount, l.balance, l.logtype,
+		l.articleid, l.chapterid, l.remark, l.addtime
+		FROM user_wallet_log l LEFT JOIN users u ON u.id = l.userid` + where + ` ORDER BY l.logid DESC LIMIT ?, ?`
+	args = append(args, (page-1)*pageSize, pageSize)
+	rows, err := utils.Db.Query(sqlStr, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var logs []*model.WalletLog
+	for rows.Next() {
+		l := &model.WalletLog{}
+		if err := rows.Scan(&l.LogID, &l.UserID, &l.Username, &l.Amount, &l.Balance, &l.LogType,
+			&l.ArticleID, &l.ChapterID, &l.Remark, &l.AddTime); err != nil {
+			return nil, 0, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, total, rows.Err()
+}
@@ -5,6 +5,7 @@ package main
 
 import (
 	"bookweb/config"
+	"bookweb/controller"
 	"bookweb/dao"
 	"bookweb/plugin"
 	"bookweb/plugin/ads"
@@ -14,10 +15,14 @@ import (
 	"bookweb/router"
 	"bookweb/service"
 	"bookweb/utils"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -45,6 +50,11 @@ func main() {
 		utils.LogWarn("System", "Failed to parse ID trans rule: %v", err)
 	}
 
+	// 初始化站点时区
+	if err := utils.SetSiteTimezone(appCfg.Site.Timezone); err != nil {
+		utils.LogWarn("System", "Invalid site timezone %q, using system timezone: %v", appCfg.Site.Timezone, err)
+	}
+
 	// 初始化数据库
 	utils.InitDB(&appCfg.Db)
 
@@ -56,7 +66,7 @@ func main() {
 	// 初始化 Redis 缓存 (如果启用)
 	if appCfg.Redis.Enabled {
 		if err := utils.InitRedis(&appCfg.Redis); err != nil {
-			utils.LogWarn("Redis", "Failed to init Redis cache: %v", err)
+			utils.LogWarn("Redis", "Failed to init Redis cache, will retry in background: %v", err)
 		} else {
 			utils.LogInfo("Redis", "Redis cache enabled and connected.")
 		}
@@ -72,6 +82,14 @@ func main() {
 		utils.LogWarn("Config", "Failed to load SEO config: %v", err)
 	}
 
+	// 加载章节内容过滤规则
+	if err := config.LoadFilterConfig("config/filter.conf"); err != nil {
+		utils.LogWarn("Config", "Failed to load filter config: %v", err)
+	}
+	if err := utils.ReloadTextFilter(); err != nil {
+		utils.LogWarn("Filter", "Failed to compile filter rules: %v", err)
+	}
+
 	// 加载路由配置
 	routerCfg, err := config.LoadRouterConfig("config/router.conf")
 	if err != nil {
@@ -115,6 +133,15 @@ func main() {
 	// 缓存预热 - 预填充常用数据缓存
 	go warmupCache()
 
+	// 定时增量生成静态页
+	go controller.StartStaticScheduler()
+
+	// 点击量定时批量回写
+	dao.StartVisitFlusher()
+
+	// 日/周/月点击量及推荐票周期清零
+	dao.StartPeriodRollover()
+
 	// 启动服务器
 	serverAddr := fmt.Sprintf("%s:%d", appCfg.Server.Host, appCfg.Server.Port)
 	utils.LogInfo("Server", "Server starting on %s (Hot reload enabled)...", serverAddr)
@@ -122,7 +149,29 @@ func main() {
 
 	// 使用中间件包装路由: Logging -> GZIP -> Router
 	handler := router.LoggingMiddleware(utils.GzipMiddleware(rm))
-	log.Fatal(http.ListenAndServe(serverAddr, handler))
+	srv := &http.Server{Addr: serverAddr, Handler: handler}
+
+	// 收到退出信号后停止接收请求，等待处理中的请求完成并回写剩余点击量
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		utils.LogInfo("Server", "Shutting down...")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			utils.LogWarn("Server", "Server shutdown: %v", err)
+		}
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-done
+	dao.StopVisitFlusher()
+	utils.LogInfo("Server", "Server stopped.")
 }
 
 // warmupCache 缓存预热 - 启动时预填充常用数据
@@ -41,4 +41,5 @@ type Article struct {
 	AllVote       int    `json:"allvote"`       // allvote
 	FullFlag      int    `json:"fullflag"`      // fullflag
 	ImgFlag       int    `json:"imgflag"`       // imgflag
+	Display       int    `json:"display"`       // display (0 显示，非 0 隐藏)
 }
@@ -24,6 +24,6 @@ type Chapter struct {
 	IsVIP        int    `json:"isvip"`        // isvip
 	ChapterType  int    `json:"chaptertype"`  // chaptertype
 	Power        int    `json:"power"`        // power
-	Display      int    `json:"display"`      // display
+	Display      int    `json:"display"`      // display (0 显示，非 0 隐藏)
 	Content      string `json:"content"`      // content from file
 }
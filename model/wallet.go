@@ -0,0 +1,34 @@
+// wallet.go
+// 钱包模型
+// 定义用户钱包流水及章节购买记录的数据结构
+package model
+
+// 钱包流水类型
+const (
+	WalletLogTopup    = "topup"    // 充值 / 后台调整
+	WalletLogPurchase = "purchase" // 购买章节
+)
+
+// WalletLog 钱包流水，对应 user_wallet_log 表
+type WalletLog struct {
+	LogID     int
+	UserID    int
+	Username  string // 关联 users 表
+	Amount    int    // 正数为收入，负数为支出
+	Balance   int    // 变动后余额
+	LogType   string
+	ArticleID int
+	ChapterID int
+	Remark    string
+	AddTime   int64
+}
+
+// ChapterPurchase 章节购买记录，对应 chapter_purchase 表
+type ChapterPurchase struct {
+	BuyID     int
+	UserID    int
+	ArticleID int
+	ChapterID int
+	Price     int
+	BuyTime   int64
+}
@@ -7,6 +7,7 @@ import (
 	"bookweb/config"
 	"bookweb/dao"
 	"bookweb/model"
+	"bookweb/service"
 	"bookweb/utils"
 	"fmt"
 	"html/template"
@@ -71,6 +72,8 @@ func LangtailInfo(w http.ResponseWriter, r *http.Request) {
 	if chapters == nil {
 		chapters = []*model.Chapter{}
 	}
+	volumes := service.GroupChaptersByVolume(chapters)
+	chapters = service.TextChapters(chapters)
 
 	// 截取最新章节
 	latestChapters := chapters
@@ -152,6 +155,7 @@ func LangtailInfo(w http.ResponseWriter, r *http.Request) {
 		"Langtails":      langtails,
 		"SortName":       sortName,
 		"Chapters":       chapters,
+		"Volumes":        volumes,
 		"LatestChapters": latestChapters,
 		"ChapterCount":   len(chapters),
 		"LatestArticles": latestArticles,
@@ -7,10 +7,9 @@ import (
 	"bookweb/config"
 	"bookweb/dao"
 	"bookweb/utils"
+	"bytes"
 	"encoding/xml"
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 )
 
@@ -50,12 +49,6 @@ func GenerateSitemap() error {
 		return nil
 	}
 
-	// 确保输出目录存在
-	outputDir := cfg.OutputPath
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("创建目录失败: %v", err)
-	}
-
 	domain := config.GetGlobalConfig().Site.Domain
 	if domain == "" {
 		domain = "localhost:8080"
@@ -97,15 +90,15 @@ func GenerateSitemap() error {
 
 	// 检查是否需要分割
 	if len(urls) > cfg.MaxURLsPerFile {
-		return generateSitemapIndex(urls, outputDir, baseURL)
+		return generateSitemapIndex(urls, baseURL)
 	}
 
 	// 生成单个 sitemap
-	return writeSitemapFile(urls, filepath.Join(outputDir, "sitemap.xml"))
+	return writeSitemapFile(urls, "sitemap.xml")
 }
 
 // generateSitemapIndex 生成 sitemap 索引和分割的 sitemap 文件
-func generateSitemapIndex(urls []URL, outputDir, baseURL string) error {
+func generateSitemapIndex(urls []URL, baseURL string) error {
 	cfg := GetConfig()
 	var sitemaps []Sitemap
 	fileCount := (len(urls) + cfg.MaxURLsPerFile - 1) / cfg.MaxURLsPerFile
@@ -118,9 +111,8 @@ func generateSitemapIndex(urls []URL, outputDir, baseURL string) error {
 		}
 
 		filename := fmt.Sprintf("sitemap-%d.xml", i+1)
-		filepath := filepath.Join(outputDir, filename)
 
-		if err := writeSitemapFile(urls[start:end], filepath); err != nil {
+		if err := writeSitemapFile(urls[start:end], filename); err != nil {
 			return err
 		}
 
@@ -136,36 +128,40 @@ func generateSitemapIndex(urls []URL, outputDir, baseURL string) error {
 		Sitemaps: sitemaps,
 	}
 
-	indexPath := filepath.Join(outputDir, "sitemap.xml")
-	return writeXMLFile(index, indexPath)
+	return writeXMLFile(index, "sitemap.xml")
+}
+
+// getStorage 获取 sitemap 输出存储 (以 OutputPath 为根目录)
+func getStorage() utils.Storage {
+	return utils.NewLocalStorage(GetConfig().OutputPath)
 }
 
 // writeSitemapFile 写入 sitemap 文件
-func writeSitemapFile(urls []URL, path string) error {
+func writeSitemapFile(urls []URL, name string) error {
 	urlset := URLSet{
 		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
 		URLs:  urls,
 	}
-	return writeXMLFile(urlset, path)
+	return writeXMLFile(urlset, name)
 }
 
-// writeXMLFile 写入 XML 文件
-func writeXMLFile(data interface{}, path string) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("创建文件失败: %v", err)
-	}
-	defer file.Close()
+// writeXMLFile 写入 XML 文件到 sitemap 存储
+func writeXMLFile(data interface{}, name string) error {
+	var buf bytes.Buffer
 
 	// 写入 XML 头
-	file.WriteString(xml.Header)
+	buf.WriteString(xml.Header)
 
-	encoder := xml.NewEncoder(file)
+	encoder := xml.NewEncoder(&buf)
 	encoder.Indent("", "  ")
 	if err := encoder.Encode(data); err != nil {
 		return fmt.Errorf("编码 XML 失败: %v", err)
 	}
 
-	utils.LogInfo("Sitemap", "Sitemap: 写入文件 %s", path)
+	if err := getStorage().Write(name, buf.Bytes()); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+
+	utils.LogInfo("Sitemap", "Sitemap: 写入文件 %s", name)
 	return nil
 }
@@ -5,9 +5,9 @@ package sitemap
 
 import (
 	"bookweb/utils"
+	"bytes"
 	"log"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -106,19 +106,32 @@ func (p *Plugin) serveSitemap(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	filePath := filepath.Join(p.config.OutputPath, filename)
+	storage := getStorage()
 
 	// 检查文件是否存在
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	info, err := storage.Stat(filename)
+	if err != nil {
 		// 尝试生成
 		if err := GenerateSitemap(); err != nil {
 			http.Error(w, "Sitemap generation failed", http.StatusInternalServerError)
 			return
 		}
+		if info, err = storage.Stat(filename); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	data, err := storage.Read(filename)
+	if err != nil {
+		http.NotFound(w, r)
+		return
 	}
 
+	// ETag/Last-Modified 由文件修改时间生成，ServeContent 据此返回 304
 	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-	http.ServeFile(w, r, filePath)
+	w.Header().Set("ETag", utils.FileETag(info.ModTime, info.Size))
+	http.ServeContent(w, r, filename, info.ModTime, bytes.NewReader(data))
 }
 
 // Shutdown 关闭插件
@@ -20,6 +20,13 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush 透传 Flush，支持流式响应
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // LoggingMiddleware HTTP请求日志中间件
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -36,21 +43,7 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 
 		// 记录日志
 		duration := time.Since(start)
-		clientIP := getClientIP(r)
+		clientIP := utils.GetClientIP(r)
 		utils.LogHTTP(r.Method, r.URL.Path, wrapped.status, duration, clientIP)
 	})
 }
-
-// getClientIP 获取客户端真实IP
-func getClientIP(r *http.Request) string {
-	// 优先从 X-Forwarded-For 获取
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		return xff
-	}
-	// 其次从 X-Real-IP 获取
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
-	}
-	// 最后使用 RemoteAddr
-	return r.RemoteAddr
-}
@@ -113,11 +113,18 @@ func SetupRouter(cfg *config.RouterConfig) *httprouter.Router {
 	router.GET(adminPath+"/article/edit", adaptHandlerFunc(admin.AuthMiddleware(admin.ArticleEdit)))
 	router.POST(adminPath+"/article/edit", adaptHandlerFunc(admin.AuthMiddleware(admin.ArticleEdit)))
 	router.POST(adminPath+"/article/delete", adaptHandlerFunc(admin.AuthMiddleware(admin.ArticleDelete)))
+	router.POST(adminPath+"/article/display", adaptHandlerFunc(admin.AuthMiddleware(admin.ArticleDisplay)))
+	router.GET(adminPath+"/article/chapters", adaptHandlerFunc(admin.AuthMiddleware(admin.ArticleChapters)))
+	router.GET(adminPath+"/chapter/edit", adaptHandlerFunc(admin.AuthMiddleware(admin.ChapterEdit)))
+	router.POST(adminPath+"/chapter/edit", adaptHandlerFunc(admin.AuthMiddleware(admin.ChapterEdit)))
+	router.POST(adminPath+"/chapter/display", adaptHandlerFunc(admin.AuthMiddleware(admin.ChapterDisplay)))
 	router.GET(adminPath+"/users", adaptHandlerFunc(admin.AuthMiddleware(admin.Users)))
 	router.POST(adminPath+"/user/delete", adaptHandlerFunc(admin.AuthMiddleware(admin.UserDelete)))
 	router.GET(adminPath+"/user/edit", adaptHandlerFunc(admin.AuthMiddleware(admin.UserEdit)))
 	router.POST(adminPath+"/user/edit", adaptHandlerFunc(admin.AuthMiddleware(admin.UserEdit)))
 	router.GET(adminPath+"/user/books", adaptHandlerFunc(admin.AuthMiddleware(admin.UserBooks)))
+	router.GET(adminPath+"/wallet", adaptHandlerFunc(admin.AuthMiddleware(admin.Wallet)))
+	router.POST(adminPath+"/wallet/topup", adaptHandlerFunc(admin.AuthMiddleware(admin.WalletTopUp)))
 	router.POST(adminPath+"/user/bookcase/delete", adaptHandlerFunc(admin.AuthMiddleware(admin.UserBookcaseDelete)))
 	router.POST(adminPath+"/user/bookmark/delete", adaptHandlerFunc(admin.AuthMiddleware(admin.UserBookmarkDelete)))
 	router.GET(adminPath+"/links", adaptHandlerFunc(admin.AuthMiddleware(admin.Links)))
@@ -127,6 +134,11 @@ func SetupRouter(cfg *config.RouterConfig) *httprouter.Router {
 	router.POST(adminPath+"/link/edit", adaptHandlerFunc(admin.AuthMiddleware(admin.LinkEdit)))
 	router.GET(adminPath+"/analytics", adaptHandlerFunc(admin.AuthMiddleware(admin.Analytics)))
 	router.POST(adminPath+"/analytics", adaptHandlerFunc(admin.AuthMiddleware(admin.Analytics)))
+	router.GET(adminPath+"/filters", adaptHandlerFunc(admin.AuthMiddleware(admin.Filters)))
+	router.POST(adminPath+"/filters", adaptHandlerFunc(admin.AuthMiddleware(admin.Filters)))
+	router.POST(adminPath+"/filters/preview", adaptHandlerFunc(admin.AuthMiddleware(admin.FilterPreview)))
+	router.GET(adminPath+"/integrity", adaptHandlerFunc(admin.AuthMiddleware(admin.Integrity)))
+	router.POST(adminPath+"/integrity/fix", adaptHandlerFunc(admin.AuthMiddleware(admin.IntegrityFix)))
 	router.POST(adminPath+"/db/test", adaptHandlerFunc(admin.AuthMiddleware(admin.TestDBConnection)))
 	router.GET(adminPath+"/security", adaptHandlerFunc(admin.AuthMiddleware(admin.Security))) // 新增安全设置
 	router.POST(adminPath+"/security/password", adaptHandlerFunc(admin.AuthMiddleware(admin.SecurityPassword)))
@@ -134,6 +146,7 @@ func SetupRouter(cfg *config.RouterConfig) *httprouter.Router {
 	router.POST(adminPath+"/redis/test", adaptHandlerFunc(admin.AuthMiddleware(admin.TestRedisConnection)))
 	router.POST(adminPath+"/cache/clear", adaptHandlerFunc(admin.AuthMiddleware(admin.ClearCache)))
 	router.POST(adminPath+"/template/clear", adaptHandlerFunc(admin.AuthMiddleware(admin.ClearTemplates)))
+	router.POST(adminPath+"/static/generate", adaptHandlerFunc(admin.AuthMiddleware(admin.StaticGenerate)))
 
 	// 模块设置更新接口
 	router.POST(adminPath+"/modules/routes", adaptHandlerFunc(admin.AuthMiddleware(admin.ModuleRoutesUpdate)))
@@ -152,11 +165,14 @@ func SetupRouter(cfg *config.RouterConfig) *httprouter.Router {
 		if handler == nil {
 			continue
 		}
+		// 静态页及整页缓存 (无对应规则的路由原样返回)
+		handler = controller.PageCache(name, controller.StaticPage(name, handler))
 
 		methods := []string{"GET"}
 		if name == "login" || name == "register" || name == "user_update" ||
 			name == "bookcase_add" || name == "bookcase_delete" ||
-			name == "bookmark_add" || name == "bookmark_delete" {
+			name == "bookmark_add" || name == "bookmark_delete" ||
+			name == "chapter_buy" || name == "book_vote" {
 			methods = append(methods, "POST")
 		}
 
@@ -299,6 +315,8 @@ func getHandler(name string) http.HandlerFunc {
 		"book":            controller.BookInfo,
 		"book_index":      controller.BookIndex,
 		"book_index_page": controller.BookIndex,
+		"book_download":   controller.BookDownload,
+		"book_epub":       controller.BookEpub,
 		"read":            controller.ChapterRead,
 		"sort":            controller.SortList,
 		"top":             controller.Top,
@@ -308,6 +326,8 @@ func getHandler(name string) http.HandlerFunc {
 		"bookcase_delete": controller.DeleteBookcase,
 		"bookmark_add":    controller.AddBookmark,
 		"bookmark_delete": controller.DeleteBookmark,
+		"chapter_buy":     controller.ChapterBuy,
+		"book_vote":       controller.BookVote,
 	}
 	return handlers[name]
 }
@@ -17,7 +17,8 @@ var LangtailUpdateFunc func(sourceID int, sourceName string, cycleDays int)
 type BookInfoData struct {
 	Article        *model.Article
 	SortName       string
-	Chapters       []*model.Chapter
+	Chapters       []*model.Chapter // 正文章节 (不含分卷)
+	Volumes        []*ChapterVolume // 按分卷分组的章节
 	LatestChapters []*model.Chapter
 	LatestArticles []*model.Article
 	HotArticles    []*model.Article
@@ -88,6 +89,9 @@ func GetBookInfoData(articleID int, articleName string) (*BookInfoData, error) {
 
 	wg.Wait()
 
+	volumes := GroupChaptersByVolume(chapters)
+	chapters = TextChapters(chapters)
+
 	// 截取最新 12 条章节记录用于展示
 	latestChapters := chapters
 	if len(chapters) > 12 {
@@ -104,9 +108,77 @@ func GetBookInfoData(articleID int, articleName string) (*BookInfoData, error) {
 		Article:        article,
 		SortName:       sortName,
 		Chapters:       chapters,
+		Volumes:        volumes,
 		LatestChapters: latestChapters,
 		LatestArticles: latestArticles,
 		HotArticles:    hotArticles,
 		Langtails:      langtails,
 	}, nil
 }
+
+// ChapterVolume 分卷及其下属章节
+type ChapterVolume struct {
+	Volume   *model.Chapter   // 分卷记录 (chaptertype=1)，为 nil 表示首个分卷之前未归卷的章节
+	Chapters []*model.Chapter // 正文章节
+}
+
+// Name 分卷名称
+func (v *ChapterVolume) Name() string {
+	if v.Volume == nil {
+		return ""
+	}
+	return v.Volume.ChapterName
+}
+
+// GroupChaptersByVolume 按章节顺序将章节归入其前面最近的分卷
+// jieqi 中分卷以 chaptertype=1 的章节记录存储，部分采集数据的 volumeid 不可靠，因此按顺序归组
+func GroupChaptersByVolume(chapters []*model.Chapter) []*ChapterVolume {
+	var volumes []*ChapterVolume
+	var current *ChapterVolume
+	for _, ch := range chapters {
+		if ch.ChapterType == 1 {
+			current = &ChapterVolume{Volume: ch}
+			volumes = append(volumes, current)
+			continue
+		}
+		if current == nil {
+			current = &ChapterVolume{}
+			volumes = append(volumes, current)
+		}
+		current.Chapters = append(current.Chapters, ch)
+	}
+	return volumes
+}
+
+// TextChapters 过滤掉分卷，仅保留正文章节
+func TextChapters(chapters []*model.Chapter) []*model.Chapter {
+	result := make([]*model.Chapter, 0, len(chapters))
+	for _, ch := range chapters {
+		if ch.ChapterType != 1 {
+			result = append(result, ch)
+		}
+	}
+	return result
+}
+
+// SliceVolumes 按正文章节下标 [start, end) 截取分组，用于目录分页
+// 跨页的分卷在每一页都会显示卷名，没有章节落在区间内的分卷被省略
+func SliceVolumes(volumes []*ChapterVolume, start, end int) []*ChapterVolume {
+	var result []*ChapterVolume
+	offset := 0
+	for _, v := range volumes {
+		lo, hi := start-offset, end-offset
+		offset += len(v.Chapters)
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(v.Chapters) {
+			hi = len(v.Chapters)
+		}
+		if lo >= hi {
+			continue
+		}
+		result = append(result, &ChapterVolume{Volume: v.Volume, Chapters: v.Chapters[lo:hi]})
+	}
+	return result
+}
@@ -8,6 +8,7 @@ import (
 	"bookweb/dao"
 	"bookweb/utils"
 	"os"
+	"reflect"
 	"time"
 )
 
@@ -19,6 +20,7 @@ func ConfigWatcher(onRouterReload func()) {
 		"config/config.conf": {},
 		"config/link.conf":   {},
 		"config/router.conf": {},
+		"config/filter.conf": {},
 	}
 
 	// 首次运行，记录当前时间
@@ -61,6 +63,12 @@ func ConfigWatcher(onRouterReload func()) {
 func reloadConfigs(routerChanged bool, onRouterReload func()) {
 	utils.LogInfo("Config", "Service: Reloading configurations...")
 
+	// 记录当前过滤规则 (重载主配置会丢弃未保存在 config.conf 中的规则)，规则未变化时不重新编译，避免清空章节缓存
+	var oldFilterRules []config.FilterRule
+	if cfg := config.GetGlobalConfig(); cfg != nil {
+		oldFilterRules = cfg.FilterRules
+	}
+
 	// 1. 重载主体配置
 	if newCfg, err := config.LoadAppConfig("config/config.conf"); err != nil {
 		utils.LogError("Config", "Error reloading config.conf: %v", err)
@@ -90,7 +98,17 @@ func reloadConfigs(routerChanged bool, onRouterReload func()) {
 		utils.LogWarn("Config", "Error reloading seo.conf: %v", err)
 	}
 
-	// 3. 如果路由配置变了，触发外部传入的回调
+	// 3. 重载章节内容过滤规则，规则变化时重新编译 (同时清空已渲染的章节缓存)
+	if err := config.LoadFilterConfig("config/filter.conf"); err != nil {
+		utils.LogWarn("Config", "Error reloading filter.conf: %v", err)
+	}
+	if cfg := config.GetGlobalConfig(); cfg != nil && !reflect.DeepEqual(cfg.FilterRules, oldFilterRules) {
+		if err := utils.ReloadTextFilter(); err != nil {
+			utils.LogWarn("Config", "Error compiling filter rules: %v", err)
+		}
+	}
+
+	// 4. 如果路由配置变了，触发外部传入的回调
 	if routerChanged && onRouterReload != nil {
 		onRouterReload()
 	}
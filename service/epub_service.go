@@ -0,0 +1,302 @@
+// epub_service.go
+// EPUB 导出服务
+// 将小说整本生成 EPUB 3 电子书 (含封面、元数据、目录导航)，并按 lastupdate 及过滤规则版本缓存到磁盘
+package service
+
+import (
+	"archive/zip"
+	"bookweb/config"
+	"bookweb/dao"
+	"bookweb/model"
+	"bookweb/utils"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEpubCacheDir 默认 EPUB 缓存目录
+const DefaultEpubCacheDir = "cache/epub"
+
+// epubLockStripes EPUB 生成锁分段数
+const epubLockStripes = 64
+
+// epubLocks 按小说 ID 分段的固定锁集合，同一本书同时只生成一次，且生成与删除互斥
+var epubLocks [epubLockStripes]sync.Mutex
+
+// epubLock 获取小说对应的生成锁
+func epubLock(articleID int) *sync.Mutex {
+	return &epubLocks[uint(articleID)%epubLockStripes]
+}
+
+// epubCacheDir 获取 EPUB 缓存目录
+func epubCacheDir() string {
+	if cfg := config.GetGlobalConfig(); cfg != nil && cfg.Cache.EpubDir != "" {
+		return cfg.Cache.EpubDir
+	}
+	return DefaultEpubCacheDir
+}
+
+// epubCachePath 缓存文件路径: {dir}/{articleID/1000}/{articleID}_{lastupdate}_{过滤规则版本}.epub
+func epubCachePath(article *model.Article) string {
+	return filepath.Join(epubCacheDir(), fmt.Sprint(article.ArticleID/1000),
+		fmt.Sprintf("%d_%d_%s.epub", article.ArticleID, article.LastUpdate, utils.TextFilterVersion()))
+}
+
+// RemoveBookEpub 删除小说已缓存的 EPUB (章节内容被修改但 lastupdate 未变化时调用)
+// 与生成共用同一把锁，避免正在生成的旧内容在删除后落盘
+func RemoveBookEpub(articleID int) {
+	mu := epubLock(articleID)
+	mu.Lock()
+	defer mu.Unlock()
+
+	old, _ := filepath.Glob(filepath.Join(epubCacheDir(), fmt.Sprint(articleID/1000), fmt.Sprintf("%d_*.epub", articleID)))
+	for _, f := range old {
+		os.Remove(f)
+	}
+}
+
+// GetBookEpub 获取小说 EPUB 文件路径，缓存不存在或小说已更新时重新生成
+func GetBookEpub(article *model.Article) (string, error) {
+	path := epubCachePath(article)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	mu := epubLock(article.ArticleID)
+	mu.Lock()
+	defer mu.Unlock()
+
+	// 等待锁期间可能已被其他请求生成
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".epub-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeEpub(tmp, article); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+
+	// 清理旧版本
+	old, _ := filepath.Glob(filepath.Join(filepath.Dir(path), fmt.Sprintf("%d_*.epub", article.ArticleID)))
+	for _, f := range old {
+		if f != path {
+			os.Remove(f)
+		}
+	}
+	return path, nil
+}
+
+// writeEpub 生成 EPUB 3 文件，章节逐个写入压缩包，不在内存中保留整本内容
+func writeEpub(w io.Writer, article *model.Article) error {
+	chapters, err := dao.GetChaptersByArticleID(article.ArticleID)
+	if err != nil {
+		return err
+	}
+	sortName := ""
+	if sort, err := dao.GetSortByIDCached(article.SortID); err == nil {
+		sortName = sort.Caption
+	}
+
+	zw := zip.NewWriter(w)
+
+	// 1. mimetype 必须是第一个文件且不压缩
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	io.WriteString(mw, "application/epub+zip")
+
+	if err := writeZipFile(zw, "META-INF/container.xml", epubContainerXML); err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "OEBPS/style.css", epubStyleCSS); err != nil {
+		return err
+	}
+
+	// 2. 封面
+	hasCover := false
+	if cover, err := utils.GetFileContent(utils.GetPhysicalCoverPath(article.ArticleID)); err == nil && len(cover) > 0 {
+		hasCover = true
+		if err := writeZipFile(zw, "OEBPS/cover.jpg", string(cover)); err != nil {
+			return err
+		}
+		if err := writeZipFile(zw, "OEBPS/cover.xhtml", epubPage("封面", "style.css",
+			`<div class="cover"><img src="cover.jpg" alt="`+html.EscapeString(article.ArticleName)+`"/></div>`)); err != nil {
+			return err
+		}
+	}
+
+	// 3. 简介页
+	var intro strings.Builder
+	intro.WriteString("<h1>" + html.EscapeString(article.ArticleName) + "</h1>")
+	intro.WriteString(`<p class="author">作者：` + html.EscapeString(article.Author) + "</p>")
+	if sortName != "" {
+		intro.WriteString(`<p class="author">分类：` + html.EscapeString(sortName) + "</p>")
+	}
+	for _, line := range strings.Split(article.Intro, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			intro.WriteString("<p>" + html.EscapeString(line) + "</p>")
+		}
+	}
+	if err := writeZipFile(zw, "OEBPS/intro.xhtml", epubPage(article.ArticleName, "style.css", intro.String())); err != nil {
+		return err
+	}
+
+	// 4. 章节
+	for i, ch := range chapters {
+		var body strings.Builder
+		if ch.ChapterType == 1 {
+			// 分卷单独成页，仅含卷名
+			body.WriteString(`<h1 class="volume">` + html.EscapeString(ch.ChapterName) + "</h1>")
+			if err := writeZipFile(zw, epubChapterFile(i), epubPage(ch.ChapterName, "../style.css", body.String())); err != nil {
+				return err
+			}
+			continue
+		}
+		body.WriteString("<h2>" + html.EscapeString(ch.ChapterName) + "</h2>")
+		content := "本章为 VIP 章节，请在线订阅阅读。"
+		if !IsPaidChapter(ch) {
+			if content, err = utils.GetChapterFilteredText(article.ArticleID, ch.ChapterID, ch.ChapterOrder); err != nil {
+				content = "章节内容缺失"
+			}
+		}
+		for _, line := range strings.Split(content, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				body.WriteString("<p>" + html.EscapeString(line) + "</p>")
+			}
+		}
+		if err := writeZipFile(zw, epubChapterFile(i), epubPage(ch.ChapterName, "../style.css", body.String())); err != nil {
+			return err
+		}
+	}
+
+	// 5. 导航文档及包文件
+	if err := writeZipFile(zw, "OEBPS/nav.xhtml", epubNav(article, chapters)); err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "OEBPS/content.opf", epubOPF(article, sortName, chapters, hasCover)); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeZipFile 写入单个压缩文件
+func writeZipFile(zw *zip.Writer, name, content string) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(fw, content)
+	return err
+}
+
+// epubChapterFile 章节文件在压缩包中的路径
+func epubChapterFile(i int) string {
+	return fmt.Sprintf("OEBPS/text/chapter_%d.xhtml", i+1)
+}
+
+// epubPage 生成 XHTML 页面，stylePath 为样式表相对路径
+func epubPage(title, stylePath, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="zh-CN" lang="zh-CN">
+<head><meta charset="UTF-8"/><title>` + html.EscapeString(title) + `</title><link rel="stylesheet" type="text/css" href="` + stylePath + `"/></head>
+<body>` + body + `</body>
+</html>`
+}
+
+// epubNav 生成 EPUB 3 导航文档
+func epubNav(article *model.Article, chapters []*model.Chapter) string {
+	var b strings.Builder
+	b.WriteString(`<nav epub:type="toc" id="toc"><h1>目录</h1><ol>`)
+	b.WriteString(`<li><a href="intro.xhtml">` + html.EscapeString(article.ArticleName) + `</a></li>`)
+	for i, ch := range chapters {
+		href := strings.TrimPrefix(epubChapterFile(i), "OEBPS/")
+		b.WriteString(`<li><a href="` + href + `">` + html.EscapeString(ch.ChapterName) + `</a></li>`)
+	}
+	b.WriteString(`</ol></nav>`)
+	return epubPage("目录", "style.css", b.String())
+}
+
+// epubOPF 生成包文件 (元数据、清单、阅读顺序)
+func epubOPF(article *model.Article, sortName string, chapters []*model.Chapter, hasCover bool) string {
+	var manifest, spine strings.Builder
+	manifest.WriteString(`<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>`)
+	manifest.WriteString(`<item id="css" href="style.css" media-type="text/css"/>`)
+	manifest.WriteString(`<item id="intro" href="intro.xhtml" media-type="application/xhtml+xml"/>`)
+	if hasCover {
+		manifest.WriteString(`<item id="cover-image" href="cover.jpg" media-type="image/jpeg" properties="cover-image"/>`)
+		manifest.WriteString(`<item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>`)
+		spine.WriteString(`<itemref idref="cover"/>`)
+	}
+	spine.WriteString(`<itemref idref="intro"/>`)
+	for i := range chapters {
+		id := fmt.Sprintf("chapter_%d", i+1)
+		href := strings.TrimPrefix(epubChapterFile(i), "OEBPS/")
+		manifest.WriteString(`<item id="` + id + `" href="` + href + `" media-type="application/xhtml+xml"/>`)
+		spine.WriteString(`<itemref idref="` + id + `"/>`)
+	}
+
+	var meta strings.Builder
+	meta.WriteString(`<dc:identifier id="bookid">urn:bookweb:` + fmt.Sprint(article.ArticleID) + `</dc:identifier>`)
+	meta.WriteString(`<dc:title>` + html.EscapeString(article.ArticleName) + `</dc:title>`)
+	meta.WriteString(`<dc:creator>` + html.EscapeString(article.Author) + `</dc:creator>`)
+	meta.WriteString(`<dc:language>zh-CN</dc:language>`)
+	if article.Intro != "" {
+		meta.WriteString(`<dc:description>` + html.EscapeString(article.Intro) + `</dc:description>`)
+	}
+	if sortName != "" {
+		meta.WriteString(`<dc:subject>` + html.EscapeString(sortName) + `</dc:subject>`)
+	}
+	if site := config.GetGlobalConfig(); site != nil && site.Site.SiteName != "" {
+		meta.WriteString(`<dc:publisher>` + html.EscapeString(site.Site.SiteName) + `</dc:publisher>`)
+	}
+	modified := time.Unix(article.LastUpdate, 0).UTC()
+	if article.LastUpdate == 0 {
+		modified = time.Now().UTC()
+	}
+	meta.WriteString(`<meta property="dcterms:modified">` + modified.Format("2006-01-02T15:04:05Z") + `</meta>`)
+	if hasCover {
+		meta.WriteString(`<meta name="cover" content="cover-image"/>`)
+	}
+
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid" xml:lang="zh-CN">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + meta.String() + `</metadata>
+<manifest>` + manifest.String() + `</manifest>
+<spine>` + spine.String() + `</spine>
+</package>`
+}
+
+const epubContainerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`
+
+const epubStyleCSS = `body { margin: 0 5%; line-height: 1.8; }
+h1, h2 { text-align: center; margin: 1em 0; }
+h1.volume { margin-top: 30%; }
+p { text-indent: 2em; margin: 0.5em 0; }
+p.author { text-indent: 0; text-align: center; }
+div.cover { text-align: center; }
+div.cover img { max-width: 100%; max-height: 100%; }`
@@ -0,0 +1,297 @@
+// integrity_service.go
+// 存储校验服务
+// 交叉比对章节表与存储后端的章节文件，发现缺失、孤立、空文件及字数不符等问题，并提供修复
+package service
+
+import (
+	"bookweb/dao"
+	"bookweb/utils"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// 问题类型
+const (
+	IssueMissing      = "missing"       // 章节记录存在，文件缺失
+	IssueOrphaned     = "orphaned"      // 文件存在，无对应章节记录
+	IssueZeroByte     = "zero_byte"     // 空文件
+	IssueSizeMismatch = "size_mismatch" // 文件字数与 size 字段不符
+)
+
+// IntegrityIssue 单个校验问题
+type IntegrityIssue struct {
+	Type        string `json:"type"`
+	ArticleID   int    `json:"articleid"`
+	ChapterID   int    `json:"chapterid,omitempty"`
+	ChapterName string `json:"chaptername,omitempty"`
+	Path        string `json:"path"`
+	Expected    int    `json:"expected,omitempty"` // size 字段
+	Actual      int    `json:"actual,omitempty"`   // 实际字数
+}
+
+// IntegrityReport 校验报告
+type IntegrityReport struct {
+	ArticleID int              `json:"articleid"` // 0 表示全站
+	Chapters  int              `json:"chapters"`  // 校验的章节数
+	Files     int              `json:"files"`     // 存储中的文件数
+	Counts    map[string]int   `json:"counts"`
+	Issues    []IntegrityIssue `json:"issues"`
+}
+
+// IntegrityOptions 校验选项
+type IntegrityOptions struct {
+	ArticleID int                      // 0 表示全站
+	Tolerance float64                  // 字数允许误差比例，如 0.1 表示 10%
+	Progress  func(checked, total int) // 进度回调 (可选)，total 为章节记录数
+}
+
+// integrityProgressStep 每校验多少个章节回调一次进度
+const integrityProgressStep = 500
+
+// chapterPrefix 章节文件前缀
+func chapterPrefix(articleID int) string {
+	if articleID > 0 {
+		return fmt.Sprintf("article/txt/%d/%d/", articleID/1000, articleID)
+	}
+	return "article/txt/"
+}
+
+// parseChapterPath 从 article/txt/{x}/{articleID}/{name}.txt 中解析小说 ID
+func parseChapterPath(path string) (int, bool) {
+	parts := strings.Split(strings.TrimPrefix(path, "article/txt/"), "/")
+	if len(parts) != 3 || !strings.HasSuffix(parts[2], ".txt") {
+		return 0, false
+	}
+	id, err := strconv.Atoi(parts[1])
+	return id, err == nil
+}
+
+// CheckStorageIntegrity 执行存储校验
+func CheckStorageIntegrity(opts IntegrityOptions) (*IntegrityReport, error) {
+	storage, err := utils.GetStorage()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := storage.List(chapterPrefix(opts.ArticleID))
+	if err != nil {
+		return nil, err
+	}
+	fileMap := make(map[string]*utils.StorageFileInfo, len(files))
+	for _, f := range files {
+		if _, ok := parseChapterPath(f.Path); ok {
+			fileMap[f.Path] = f
+		}
+	}
+
+	chapters, err := dao.GetChaptersForCheck(opts.ArticleID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &IntegrityReport{
+		ArticleID: opts.ArticleID,
+		Files:     len(fileMap),
+		Counts:    map[string]int{IssueMissing: 0, IssueOrphaned: 0, IssueZeroByte: 0, IssueSizeMismatch: 0},
+	}
+	add := func(issue IntegrityIssue) {
+		report.Issues = append(report.Issues, issue)
+		report.Counts[issue.Type]++
+	}
+
+	for i, ch := range chapters {
+		if opts.Progress != nil && i%integrityProgressStep == 0 {
+			opts.Progress(i, len(chapters))
+		}
+		if ch.ChapterType == 1 {
+			continue // 分卷无正文文件
+		}
+		report.Chapters++
+		path := utils.ChapterFilePath(ch.ArticleID, ch.ChapterID, ch.ChapterOrder)
+		issue := IntegrityIssue{ArticleID: ch.ArticleID, ChapterID: ch.ChapterID, ChapterName: ch.ChapterName, Path: path}
+
+		info, ok := fileMap[path]
+		if !ok {
+			issue.Type = IssueMissing
+			add(issue)
+			continue
+		}
+		delete(fileMap, path)
+
+		if info.Size == 0 {
+			issue.Type = IssueZeroByte
+			add(issue)
+			continue
+		}
+		if actual, ok := checkChapterSize(storage, path, info.Size, ch.Size, opts.Tolerance); !ok {
+			issue.Type = IssueSizeMismatch
+			issue.Expected = ch.Size
+			issue.Actual = actual
+			add(issue)
+		}
+	}
+
+	if opts.Progress != nil {
+		opts.Progress(len(chapters), len(chapters))
+	}
+
+	// 剩余文件均无章节记录
+	for path := range fileMap {
+		articleID, _ := parseChapterPath(path)
+		add(IntegrityIssue{Type: IssueOrphaned, ArticleID: articleID, Path: path})
+	}
+	return report, nil
+}
+
+// checkChapterSize 校验字数，size 字段可能记录字节数或字符数
+// 字节数一致时不读取文件，否则读取并统计字符数，返回实际字符数及是否在误差范围内
+func checkChapterSize(storage utils.Storage, path string, fileSize int64, expected int, tolerance float64) (int, bool) {
+	if int64(expected) == fileSize {
+		return expected, true
+	}
+	data, err := storage.Read(path)
+	if err != nil {
+		return 0, false
+	}
+	text, _, err := utils.ToUtf8(data)
+	if err != nil {
+		return 0, false
+	}
+	actual := utf8.RuneCount(text)
+	diff := actual - expected
+	if diff < 0 {
+		diff = -diff
+	}
+	return actual, float64(diff) <= float64(expected)*tolerance
+}
+
+// IntegrityFixResult 修复结果
+type IntegrityFixResult struct {
+	Hidden  int `json:"hidden"`
+	Deleted int `json:"deleted"`
+}
+
+// FixIntegrityIssues 修复校验问题
+// hideBroken: 隐藏文件缺失或为空的章节；deleteOrphans: 删除孤立文件
+func FixIntegrityIssues(issues []IntegrityIssue, hideBroken, deleteOrphans bool) (*IntegrityFixResult, error) {
+	storage, err := utils.GetStorage()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &IntegrityFixResult{}
+	var hideIDs []int
+	articles := make(map[int]bool) // 涉及隐藏章节的小说
+	var errs []error
+
+	for _, issue := range issues {
+		switch issue.Type {
+		case IssueMissing, IssueZeroByte:
+			if hideBroken && issue.ChapterID > 0 {
+				hideIDs = append(hideIDs, issue.ChapterID)
+				articles[issue.ArticleID] = true
+			}
+		case IssueOrphaned:
+			if deleteOrphans {
+				if err := storage.Delete(issue.Path); err != nil {
+					errs = append(errs, fmt.Errorf("删除 %s 失败: %v", issue.Path, err))
+					continue
+				}
+				result.Deleted++
+			}
+		}
+	}
+
+	if len(hideIDs) > 0 {
+		hidden, err := dao.HideChapters(hideIDs)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		result.Hidden = hidden
+		// 部分批次失败时已隐藏的章节同样需要回填统计
+		if hidden > 0 {
+			for articleID := range articles {
+				if err := dao.RefreshArticleStats(articleID); err != nil {
+					errs = append(errs, err)
+				}
+				dao.InvalidateArticleVisibility(articleID)
+			}
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// IntegrityTask 后台校验任务状态 (全站校验耗时较长，在后台执行)
+type IntegrityTask struct {
+	Running   bool
+	StartedAt time.Time
+	Duration  time.Duration
+	Checked   int                 // 已校验的章节记录数
+	Total     int                 // 章节记录总数 (读取文件列表期间为 0)
+	Report    *IntegrityReport    // 校验结果
+	Fix       *IntegrityFixResult // 校验后执行修复时的结果
+	Error     string
+}
+
+var (
+	integrityRunning atomic.Bool
+	integrityMu      sync.Mutex
+	integrityLast    IntegrityTask
+)
+
+// StartIntegrityTask 在后台执行校验，hideBroken / deleteOrphans 任一为 true 时校验完成后按结果修复
+// 同一时间只允许一个任务，已有任务在执行时返回 false
+func StartIntegrityTask(opts IntegrityOptions, hideBroken, deleteOrphans bool) bool {
+	if !integrityRunning.CompareAndSwap(false, true) {
+		return false
+	}
+	task := IntegrityTask{Running: true, StartedAt: time.Now()}
+	setIntegrityTask(task)
+
+	opts.Progress = func(checked, total int) {
+		integrityMu.Lock()
+		integrityLast.Checked = checked
+		integrityLast.Total = total
+		integrityMu.Unlock()
+	}
+
+	go func() {
+		defer integrityRunning.Store(false)
+
+		report, err := CheckStorageIntegrity(opts)
+		if err == nil && (hideBroken || deleteOrphans) {
+			task.Fix, err = FixIntegrityIssues(report.Issues, hideBroken, deleteOrphans)
+		}
+		if err != nil {
+			task.Error = err.Error()
+			utils.LogError("Integrity", "Integrity task failed: %v", err)
+		}
+		progress := GetIntegrityTask()
+		task.Checked, task.Total = progress.Checked, progress.Total
+		task.Report = report
+		task.Running = false
+		task.Duration = time.Since(task.StartedAt).Round(time.Second)
+		setIntegrityTask(task)
+	}()
+	return true
+}
+
+// GetIntegrityTask 获取最近一次 (或正在进行的) 后台校验任务
+func GetIntegrityTask() IntegrityTask {
+	integrityMu.Lock()
+	defer integrityMu.Unlock()
+	return integrityLast
+}
+
+// setIntegrityTask 保存任务状态供后台查看
+func setIntegrityTask(task IntegrityTask) {
+	integrityMu.Lock()
+	integrityLast = task
+	integrityMu.Unlock()
+}
@@ -0,0 +1,239 @@
+// migrate_service.go
+// 存储迁移服务
+// 按章节表与小说表枚举章节文本和封面，在两个存储后端之间并发复制，支持断点续传、校验和及试运行
+package service
+
+import (
+	"bookweb/dao"
+	"bookweb/utils"
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// MigrateOptions 迁移选项
+type MigrateOptions struct {
+	Workers   int    // 并发数
+	DryRun    bool   // 只统计，不写入目标存储
+	Verify    bool   // 写入后回读目标文件并比对 MD5
+	StatePath string // 进度文件，记录已完成的路径，为空时不支持续传
+	Direction string // 迁移方向 (源 -> 目标)，写入进度文件首行，与已有进度文件不一致时拒绝续传
+}
+
+// migrateStateHeader 进度文件首行前缀，其后为迁移方向
+const migrateStateHeader = "#direction\t"
+
+// MigrateError 单个文件迁移失败
+type MigrateError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// MigrateReport 迁移报告
+type MigrateReport struct {
+	Total   int            `json:"total"`   // 待迁移文件数
+	Copied  int            `json:"copied"`  // 复制成功 (试运行时为待复制数)
+	Skipped int            `json:"skipped"` // 已完成或目标已存在且一致
+	Missing int            `json:"missing"` // 源存储中不存在
+	Failed  int            `json:"failed"`
+	Bytes   int64          `json:"bytes"` // 复制字节数
+	Errors  []MigrateError `json:"errors"`
+}
+
+// MigrateProgress 迁移进度回调，done 为已处理文件数
+type MigrateProgress func(done, total int)
+
+// migrateResult 单个文件的处理结果
+type migrateResult int
+
+const (
+	migrateCopied migrateResult = iota
+	migrateSkipped
+	migrateMissing
+	migrateFailed
+)
+
+// CollectStoragePaths 按章节表和小说表生成需要迁移的文件路径
+// 路径规则与 ChapterFilePath / GetPhysicalCoverPath 一致，分卷没有正文文件
+func CollectStoragePaths(articleID int) ([]string, error) {
+	chapters, err := dao.GetChaptersForCheck(articleID)
+	if err != nil {
+		return nil, err
+	}
+	articleIDs, err := dao.GetArticleIDsForCheck(articleID)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(chapters)+len(articleIDs))
+	for _, ch := range chapters {
+		if ch.ChapterType == 1 {
+			continue
+		}
+		paths = append(paths, utils.ChapterFilePath(ch.ArticleID, ch.ChapterID, ch.ChapterOrder))
+	}
+	for _, id := range articleIDs {
+		paths = append(paths, utils.GetPhysicalCoverPath(id))
+	}
+	return paths, nil
+}
+
+// loadMigrateState 读取进度文件，返回已完成的路径集合及文件中记录的迁移方向
+// 首行格式: #direction<TAB>方向；其余每行格式: path<TAB>md5
+func loadMigrateState(statePath string) (map[string]bool, string, error) {
+	done := make(map[string]bool)
+	f, err := os.Open(statePath)
+	if os.IsNotExist(err) {
+		return done, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	direction := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, migrateStateHeader) {
+			direction = strings.TrimPrefix(line, migrateStateHeader)
+			continue
+		}
+		if path, _, ok := strings.Cut(line, "\t"); ok && path != "" {
+			done[path] = true
+		}
+	}
+	return done, direction, scanner.Err()
+}
+
+// md5Hex 计算 MD5
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// MigrateStorage 将 paths 中的文件从 src 复制到 dst
+// 源文件不存在时计入 Missing (封面、缺失章节均属正常情况)；目标已存在且内容一致时跳过
+func MigrateStorage(src, dst utils.Storage, paths []string, opts MigrateOptions, progress MigrateProgress) (*MigrateReport, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+
+	done := make(map[string]bool)
+	var state *os.File
+	if opts.StatePath != "" {
+		var err error
+		var direction string
+		if done, direction, err = loadMigrateState(opts.StatePath); err != nil {
+			return nil, fmt.Errorf("读取进度文件失败: %v", err)
+		}
+		// 进度仅对同一迁移方向有效，避免换方向后误跳过未复制的文件
+		if (len(done) > 0 || direction != "") && direction != opts.Direction {
+			return nil, fmt.Errorf("进度文件 %s 属于其它迁移 (%s)，请删除该文件或指定其它 -state", opts.StatePath, direction)
+		}
+		if !opts.DryRun {
+			state, err = os.OpenFile(opts.StatePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				return nil, fmt.Errorf("打开进度文件失败: %v", err)
+			}
+			defer state.Close()
+			if direction == "" {
+				fmt.Fprintf(state, "%s%s\n", migrateStateHeader, opts.Direction)
+			}
+		}
+	}
+
+	report := &MigrateReport{Total: len(paths)}
+	var mu sync.Mutex
+	var processed int64
+
+	finish := func(path string, result migrateResult, size int64, sum string, err error) {
+		mu.Lock()
+		switch result {
+		case migrateCopied:
+			report.Copied++
+			report.Bytes += size
+		case migrateSkipped:
+			report.Skipped++
+		case migrateMissing:
+			report.Missing++
+		case migrateFailed:
+			report.Failed++
+			report.Errors = append(report.Errors, MigrateError{Path: path, Error: err.Error()})
+		}
+		// 试运行不记录进度；缺失文件不记录，以便源文件补齐后再次迁移；进度文件中已有的路径 (sum 为空) 不重复记录
+		if state != nil && sum != "" && (result == migrateCopied || result == migrateSkipped) {
+			fmt.Fprintf(state, "%s\t%s\n", path, sum)
+		}
+		mu.Unlock()
+
+		if n := atomic.AddInt64(&processed, 1); progress != nil {
+			progress(int(n), len(paths))
+		}
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				result, size, sum, err := migrateFile(src, dst, path, opts)
+				finish(path, result, size, sum, err)
+			}
+		}()
+	}
+	for _, path := range paths {
+		if done[path] {
+			finish(path, migrateSkipped, 0, "", nil)
+			continue
+		}
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+	return report, nil
+}
+
+// migrateFile 复制单个文件，返回处理结果、字节数及源文件 MD5
+func migrateFile(src, dst utils.Storage, path string, opts MigrateOptions) (migrateResult, int64, string, error) {
+	data, err := src.Read(path)
+	if errors.Is(err, utils.ErrStorageNotExist) {
+		return migrateMissing, 0, "", nil
+	}
+	if err != nil {
+		return migrateFailed, 0, "", fmt.Errorf("读取源文件失败: %v", err)
+	}
+	sum := md5Hex(data)
+
+	// 目标已存在且大小一致时比对内容，相同则跳过
+	if info, err := dst.Stat(path); err == nil && info.Size == int64(len(data)) {
+		if existing, err := dst.Read(path); err == nil && md5Hex(existing) == sum {
+			return migrateSkipped, 0, sum, nil
+		}
+	}
+	if opts.DryRun {
+		return migrateCopied, int64(len(data)), sum, nil
+	}
+
+	if err := dst.Write(path, data); err != nil {
+		return migrateFailed, 0, sum, fmt.Errorf("写入目标失败: %v", err)
+	}
+	if opts.Verify {
+		written, err := dst.Read(path)
+		if err != nil {
+			return migrateFailed, 0, sum, fmt.Errorf("回读目标失败: %v", err)
+		}
+		if got := md5Hex(written); got != sum {
+			return migrateFailed, 0, sum, fmt.Errorf("校验和不一致: 源 %s, 目标 %s", sum, got)
+		}
+	}
+	return migrateCopied, int64(len(data)), sum, nil
+}
@@ -0,0 +1,94 @@
+// visit_service.go
+// 点击统计服务
+// 按 IP 每小时上限及访客去重窗口过滤小说点击，再交由 dao 聚合回写
+package service
+
+import (
+	"bookweb/config"
+	"bookweb/dao"
+	"bookweb/utils"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultVisitDedupWindow 默认访客去重窗口
+const defaultVisitDedupWindow = 30 * time.Minute
+
+var (
+	// visitIPLimiter 按 IP 每小时计数上限
+	visitIPLimiter = utils.NewRateLimiter(time.Hour)
+
+	// visitDedupLimiter 内存去重记录，窗口内同一 key 只允许一次；去重窗口修改后重建
+	visitDedupMu      sync.Mutex
+	visitDedupLimiter *utils.RateLimiter
+	visitDedupWindow  time.Duration
+
+	visitCapped     atomic.Int64
+	visitDuplicates atomic.Int64
+)
+
+// VisitFilterStats 点击过滤统计
+type VisitFilterStats struct {
+	Capped     int64 // 超过 IP 每小时上限未计数的点击
+	Duplicates int64 // 去重窗口内重复访问的点击
+}
+
+// GetVisitFilterStats 获取点击过滤统计
+func GetVisitFilterStats() VisitFilterStats {
+	return VisitFilterStats{Capped: visitCapped.Load(), Duplicates: visitDuplicates.Load()}
+}
+
+// VisitDedupEnabled 当前统计方式是否需要识别访客 (uv 或 both)
+func VisitDedupEnabled() bool {
+	mode := config.GetGlobalConfig().Visit.Mode
+	return mode == dao.VisitModeUV || mode == dao.VisitModeBoth
+}
+
+// CountArticleVisit 统计一次小说点击，visitor 为访客标识 (未开启去重时可为空)
+func CountArticleVisit(articleID int, ip, visitor string) {
+	cfg := config.GetGlobalConfig().Visit
+	if !visitIPLimiter.Allow(ip, cfg.IPHourlyLimit) {
+		visitCapped.Add(1)
+		return
+	}
+
+	unique := true
+	if VisitDedupEnabled() {
+		if unique = isUniqueVisit(articleID, visitor, &cfg); !unique {
+			visitDuplicates.Add(1)
+		}
+	}
+	dao.IncArticleVisit(articleID, unique)
+}
+
+// isUniqueVisit 判断访客是否在去重窗口内首次访问该小说
+// 开启 dedup_redis 时以 SET NX + TTL 记录 (多实例共享)，Redis 不可用时使用进程内记录
+func isUniqueVisit(articleID int, visitor string, cfg *config.VisitConfig) bool {
+	window := defaultVisitDedupWindow
+	if cfg.DedupWindow > 0 {
+		window = time.Duration(cfg.DedupWindow) * time.Second
+	}
+	key := "visit_seen:" + strconv.Itoa(articleID) + ":" + visitor
+
+	if cfg.DedupRedis && utils.IsRedisEnabled() {
+		ok, err := utils.CacheSetNX(key, 1, window)
+		if err == nil {
+			return ok
+		}
+		utils.LogWarn("Visit", "Redis dedup failed, using memory: %v", err)
+	}
+	return dedupLimiter(window).Allow(key, 1)
+}
+
+// dedupLimiter 获取指定窗口的内存去重记录
+func dedupLimiter(window time.Duration) *utils.RateLimiter {
+	visitDedupMu.Lock()
+	defer visitDedupMu.Unlock()
+	if visitDedupLimiter == nil || visitDedupWindow != window {
+		visitDedupLimiter = utils.NewRateLimiter(window)
+		visitDedupWindow = window
+	}
+	return visitDedupLimiter
+}
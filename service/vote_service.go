@@ -0,0 +1,45 @@
+// vote_service.go
+// 推荐票服务
+// 处理登录用户的每日推荐票额度及投票流程
+package service
+
+import (
+	"bookweb/config"
+	"bookweb/dao"
+	"bookweb/utils"
+	"errors"
+	"strconv"
+)
+
+var (
+	// ErrVoteDisabled 推荐票功能未开启
+	ErrVoteDisabled = errors.New("推荐票功能未开启")
+	// ErrVoteArticleNotFound 投票的小说不存在或已隐藏
+	ErrVoteArticleNotFound = errors.New("小说不存在")
+)
+
+// voteDay 站点时区的当前日期 (YYYYMMDD)
+func voteDay() int {
+	day, _ := strconv.Atoi(utils.SiteNow().Format("20060102"))
+	return day
+}
+
+// dailyVotes 每个用户每日推荐票数，0 为关闭推荐票
+func dailyVotes() int {
+	if cfg := config.GetGlobalConfig(); cfg != nil && cfg.Site.DailyVotes > 0 {
+		return cfg.Site.DailyVotes
+	}
+	return 0
+}
+
+// VoteArticle 为小说投一张推荐票，返回今日剩余票数
+func VoteArticle(userID, articleID int) (int, error) {
+	allowance := dailyVotes()
+	if allowance == 0 {
+		return 0, ErrVoteDisabled
+	}
+	if _, err := dao.GetArticleByIDCached(articleID); err != nil {
+		return 0, ErrVoteArticleNotFound
+	}
+	return dao.AddArticleVote(userID, articleID, allowance, voteDay())
+}
@@ -0,0 +1,22 @@
+-- 统计数据表
+-- 运行此SQL创建日/周/月点击量及推荐票周期清零记录表、用户推荐票记录表
+
+-- 周期清零记录 (多实例部署时保证每个周期只清零一次，每类周期只保留最新一条)
+CREATE TABLE IF NOT EXISTS `period_reset` (
+  `period` varchar(32) NOT NULL COMMENT 'day:2006-01-02 / week:2006-W01 / month:2006-01',
+  `resettime` int(11) unsigned NOT NULL DEFAULT '0',
+  PRIMARY KEY (`period`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 用户推荐票记录 (按站点时区日期统计每日已投票数)
+CREATE TABLE IF NOT EXISTS `user_vote` (
+  `voteid` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `userid` int(11) NOT NULL,
+  `articleid` int(11) unsigned NOT NULL,
+  `votenum` int(11) NOT NULL DEFAULT '1',
+  `voteday` int(11) unsigned NOT NULL COMMENT '投票日期 YYYYMMDD',
+  `votetime` int(11) unsigned NOT NULL DEFAULT '0',
+  PRIMARY KEY (`voteid`),
+  KEY `userday` (`userid`, `voteday`),
+  KEY `articleid` (`articleid`, `votetime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
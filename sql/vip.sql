@@ -0,0 +1,38 @@
+-- VIP 章节订阅数据表
+-- 运行此SQL创建用户钱包、钱包流水及章节购买记录表
+
+-- 用户钱包 (余额单位与章节 saleprice 一致)
+CREATE TABLE IF NOT EXISTS `user_wallet` (
+  `userid` int(11) NOT NULL,
+  `balance` int(11) NOT NULL DEFAULT '0',
+  `updatetime` int(11) unsigned NOT NULL DEFAULT '0',
+  PRIMARY KEY (`userid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 钱包流水 (充值、购买、后台调整)
+CREATE TABLE IF NOT EXISTS `user_wallet_log` (
+  `logid` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `userid` int(11) NOT NULL,
+  `amount` int(11) NOT NULL COMMENT '正数为收入，负数为支出',
+  `balance` int(11) NOT NULL COMMENT '变动后余额',
+  `logtype` varchar(20) NOT NULL DEFAULT '' COMMENT 'topup / purchase',
+  `articleid` int(11) unsigned NOT NULL DEFAULT '0',
+  `chapterid` int(11) unsigned NOT NULL DEFAULT '0',
+  `remark` varchar(255) NOT NULL DEFAULT '',
+  `addtime` int(11) unsigned NOT NULL DEFAULT '0',
+  PRIMARY KEY (`logid`),
+  KEY `userid` (`userid`, `logid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 章节购买记录
+CREATE TABLE IF NOT EXISTS `chapter_purchase` (
+  `buyid` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `userid` int(11) NOT NULL,
+  `articleid` int(11) unsigned NOT NULL DEFAULT '0',
+  `chapterid` int(11) unsigned NOT NULL,
+  `price` int(11) NOT NULL DEFAULT '0',
+  `buytime` int(11) unsigned NOT NULL DEFAULT '0',
+  PRIMARY KEY (`buyid`),
+  UNIQUE KEY `user_chapter` (`userid`, `chapterid`),
+  KEY `articleid` (`articleid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -7,10 +7,21 @@ function lazy() { $("img.lazy").lazyload({ effect: "fadeIn" }) }
 
 function desc(obj) {
     $(obj).text() == '倒序 ↑' ? $(obj).text('正序 ↓') : $(obj).text('倒序 ↑');
-    let lis = $("#chapterList").children();
+    // 按分卷分组倒序，卷名保持在所属章节之前
+    let groups = [];
+    $("#chapterList").children().each(function () {
+        if ($(this).hasClass('volume') || groups.length === 0) {
+            groups.push({ head: $(this).hasClass('volume') ? $(this).clone() : null, items: [] });
+            if ($(this).hasClass('volume')) return;
+        }
+        groups[groups.length - 1].items.push($(this).clone());
+    });
     $("#chapterList").empty();
-    for (let i = lis.length - 1; i >= 0; i--) {
-        $("#chapterList").append(lis.eq(i).clone())
+    for (let i = groups.length - 1; i >= 0; i--) {
+        if (groups[i].head) $("#chapterList").append(groups[i].head);
+        for (let j = groups[i].items.length - 1; j >= 0; j--) {
+            $("#chapterList").append(groups[i].items[j]);
+        }
     }
 }
 
@@ -58,4 +69,23 @@ function click_fav() {
             alert("加入收藏失败，请使用Ctrl+D进行添加");
         }
     }
+}
+//bookvote 投推荐票，成功后更新页面上的推荐票总数
+function bookvote(url, aid) {
+    $.ajax({
+        type: "post",
+        url: url,
+        data: { articleid: aid },
+        dataType: "json",
+        success: function (res) {
+            alert(res.message);
+            if (res.success) {
+                var el = document.getElementById("allvote");
+                if (el) el.innerText = parseInt(el.innerText || "0", 10) + 1;
+            }
+        },
+        error: function () {
+            alert("请求失败，请稍后重试");
+        }
+    });
 }
\ No newline at end of file
@@ -287,4 +287,23 @@ function updateFooter() {
     if ($("#foot_user").length > 0) {
         $("#foot_user").html(html);
     }
+}
+//bookvote 投推荐票，成功后更新页面上的推荐票总数
+function bookvote(url, aid) {
+    $.ajax({
+        type: "post",
+        url: url,
+        data: { articleid: aid },
+        dataType: "json",
+        success: function (res) {
+            alert(res.message);
+            if (res.success) {
+                var el = document.getElementById("allvote");
+                if (el) el.innerText = parseInt(el.innerText || "0", 10) + 1;
+            }
+        },
+        error: function () {
+            alert("请求失败，请稍后重试");
+        }
+    });
 }
\ No newline at end of file
@@ -0,0 +1,126 @@
+// cache_loader.go
+// 缓存加载器
+// 在缓存读写之上提供请求合并、过期后重验证 (stale-while-revalidate) 及 TTL 随机抖动
+package utils
+
+import (
+	"bookweb/config"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 默认值
+const (
+	DefaultCacheStaleTTL = 60 // 过期后仍可返回旧值的时间 (秒)
+	DefaultCacheJitter   = 10 // TTL 随机抖动比例 (%)
+)
+
+// swrPrefix 缓存值包装前缀，格式: swr:{软过期毫秒时间戳}|{值}
+// 无此前缀的旧值视为未过期
+const swrPrefix = "swr:"
+
+// CacheLoader 带请求合并、过期重验证及 TTL 抖动的缓存加载器
+type CacheLoader struct {
+	get   func(key string) (string, bool)
+	set   func(key, value string, ttl time.Duration)
+	tag   func(key string, tags ...string)
+	group FlightGroup
+}
+
+// DataLoader 两级数据缓存 (L1 + Redis) 加载器，供 DAO 使用
+var DataLoader = &CacheLoader{get: DataCacheGet, set: DataCacheSet, tag: TagCacheKey}
+
+// PageLoader Redis 整页缓存加载器，供页面控制器使用
+var PageLoader = &CacheLoader{
+	get: func(key string) (string, bool) {
+		value, err := CacheGet(key)
+		return value, err == nil && value != ""
+	},
+	set: func(key, value string, ttl time.Duration) {
+		CacheSet(key, value, ttl)
+	},
+	tag: TagRedisCacheKey,
+}
+
+// cacheStaleTTL 过期后仍可返回旧值的时间，0 表示禁用
+func cacheStaleTTL() time.Duration {
+	stale := DefaultCacheStaleTTL
+	if cfg := config.GetGlobalConfig(); cfg != nil && cfg.Cache.StaleTTL != 0 {
+		stale = cfg.Cache.StaleTTL
+	}
+	if stale < 0 {
+		return 0
+	}
+	return time.Duration(stale) * time.Second
+}
+
+// JitterTTL 为 TTL 增加 0 ~ N% 的随机抖动，避免同时写入的键 (如预热) 同时过期
+func JitterTTL(ttl time.Duration) time.Duration {
+	jitter := DefaultCacheJitter
+	if cfg := config.GetGlobalConfig(); cfg != nil && cfg.Cache.TTLJitter != 0 {
+		jitter = cfg.Cache.TTLJitter
+	}
+	if jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	max := int64(ttl) * int64(jitter) / 100
+	if max <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Int63n(max+1))
+}
+
+// wrapCacheValue 包装缓存值及软过期时间
+func wrapCacheValue(value string, expireAt time.Time) string {
+	return swrPrefix + strconv.FormatInt(expireAt.UnixMilli(), 10) + "|" + value
+}
+
+// unwrapCacheValue 解析缓存值，返回原值及是否已过软过期时间
+func unwrapCacheValue(raw string) (string, bool) {
+	if !strings.HasPrefix(raw, swrPrefix) {
+		return raw, false
+	}
+	ts, value, ok := strings.Cut(raw[len(swrPrefix):], "|")
+	if !ok {
+		return raw, false
+	}
+	expireAt, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return raw, false
+	}
+	return value, time.Now().UnixMilli() >= expireAt
+}
+
+// Load 读取缓存，未命中时合并并发请求只执行一次 load 并写入缓存，写入时将键登记到 tags 下
+// 值过期但仍在重验证窗口内时直接返回旧值，并由一个后台任务刷新
+func (l *CacheLoader) Load(key string, ttl time.Duration, load func() (string, error), tags ...string) (string, error) {
+	if raw, ok := l.get(key); ok {
+		value, stale := unwrapCacheValue(raw)
+		if !stale {
+			return value, nil
+		}
+		if cacheStaleTTL() > 0 {
+			l.group.DoAsync(key, func() (string, error) {
+				return l.fill(key, ttl, load, tags)
+			})
+			return value, nil
+		}
+	}
+	return l.group.Do(key, func() (string, error) {
+		return l.fill(key, ttl, load, tags)
+	})
+}
+
+// fill 执行 load 并写入缓存，缓存实际保留时间为 TTL + 重验证窗口
+func (l *CacheLoader) fill(key string, ttl time.Duration, load func() (string, error), tags []string) (string, error) {
+	value, err := load()
+	if err != nil {
+		return "", err
+	}
+	l.tag(key, tags...)
+	ttl = JitterTTL(ttl)
+	l.set(key, wrapCacheValue(value, time.Now().Add(ttl)), ttl+cacheStaleTTL())
+	return value, nil
+}
@@ -0,0 +1,168 @@
+// cache_tags.go
+// 缓存标签
+// 记录缓存键依赖的标签 (如 article:1、sort:2、home)，按标签一次性清理所有相关缓存
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// cacheTagTTL Redis 标签集合的保留时间，需长于被标记缓存的最长 TTL
+const cacheTagTTL = 24 * time.Hour
+
+var (
+	// 进程内标签索引：标签 -> 缓存键集合 (仅登记 L1 数据缓存中的键，条目被淘汰或过期时随之移除)
+	cacheTags = make(map[string]map[string]struct{})
+	// 反向索引：缓存键 -> 标签集合，用于 L1 条目移除时清理 cacheTags
+	cacheKeyTags = make(map[string]map[string]struct{})
+	cacheTagsMu  sync.Mutex
+)
+
+func init() {
+	dataCache.SetRemoveHook(untagCacheKey)
+}
+
+// CacheTagKeyPrefix Redis 中标签集合键的前缀
+const CacheTagKeyPrefix = "cache_tag:"
+
+// PageCacheKeyPrefix 整页缓存键前缀，键格式: page_cache:{路由}:{pc|mobile}:... (见 controller.PageCache)
+// 整页缓存不登记全站标签，全站元素变更时按前缀扫描清理 (见 ClearPageCache)
+const PageCacheKeyPrefix = "page_cache:"
+
+// cacheTagKey Redis 中标签集合的键 (不含站点前缀)，集合成员为不含站点前缀的逻辑键
+func cacheTagKey(tag string) string {
+	return CacheTagKeyPrefix + tag
+}
+
+// TagCacheKey 将 L1 / L2 数据缓存键登记到标签下 (进程内索引及 Redis 标签集合)
+func TagCacheKey(key string, tags ...string) {
+	if len(tags) == 0 {
+		return
+	}
+
+	cacheTagsMu.Lock()
+	keyTags, ok := cacheKeyTags[key]
+	if !ok {
+		keyTags = make(map[string]struct{})
+		cacheKeyTags[key] = keyTags
+	}
+	for _, tag := range tags {
+		keys, ok := cacheTags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			cacheTags[tag] = keys
+		}
+		keys[key] = struct{}{}
+		keyTags[tag] = struct{}{}
+	}
+	cacheTagsMu.Unlock()
+
+	TagRedisCacheKey(key, tags...)
+}
+
+// TagRedisCacheKey 仅在 Redis 标签集合中登记缓存键 (只存于 Redis 的缓存使用，如整页缓存)
+func TagRedisCacheKey(key string, tags ...string) {
+	if c := activeRedis(); len(tags) > 0 && c != nil {
+		pipe := c.client.Pipeline()
+		for _, tag := range tags {
+			pipe.SAdd(redisCtx, c.key(cacheTagKey(tag)), key)
+			pipe.Expire(redisCtx, c.key(cacheTagKey(tag)), cacheTagTTL)
+		}
+		if _, err := pipe.Exec(redisCtx); err != nil {
+			LogWarn("Cache", "Tag cache key %s failed: %v", key, err)
+		}
+	}
+}
+
+// InvalidateCacheTags 删除标签下登记的所有缓存键 (L1 及 Redis)，返回删除的键数量
+func InvalidateCacheTags(tags ...string) int {
+	seen := make(map[string]struct{})
+
+	cacheTagsMu.Lock()
+	for _, tag := range tags {
+		for key := range cacheTags[tag] {
+			seen[key] = struct{}{}
+			if keyTags := cacheKeyTags[key]; keyTags != nil {
+				delete(keyTags, tag)
+				if len(keyTags) == 0 {
+					delete(cacheKeyTags, key)
+				}
+			}
+		}
+		delete(cacheTags, tag)
+	}
+	cacheTagsMu.Unlock()
+
+	// 其它进程登记的键只存在于 Redis
+	if c := activeRedis(); c != nil {
+		for _, tag := range tags {
+			members, err := c.client.SMembers(redisCtx, c.key(cacheTagKey(tag))).Result()
+			if err != nil {
+				LogWarn("Cache", "Read cache tag %s failed: %v", tag, err)
+				continue
+			}
+			for _, key := range members {
+				seen[key] = struct{}{}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	DataCacheDel(keys...)
+
+	if IsRedisEnabled() {
+		tagKeys := make([]string, len(tags))
+		for i, tag := range tags {
+			tagKeys[i] = cacheTagKey(tag)
+		}
+		CacheDel(tagKeys...)
+	}
+	return len(keys)
+}
+
+// ClearPageCache 扫描 (SCAN) 删除本站整页缓存，routes 为空时删除全部页面，否则只删除指定路由的页面
+// 返回删除的键数量
+func ClearPageCache(routes ...string) (int, error) {
+	if !IsRedisEnabled() {
+		return 0, nil
+	}
+	if len(routes) == 0 {
+		return CacheDelPattern(PageCacheKeyPrefix+"*", nil)
+	}
+	total := 0
+	for _, route := range routes {
+		n, err := CacheDelPattern(PageCacheKeyPrefix+route+":*", nil)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// untagCacheKey 从进程内标签索引中移除缓存键 (L1 条目被删除、淘汰或过期时调用)
+func untagCacheKey(key string) {
+	cacheTagsMu.Lock()
+	defer cacheTagsMu.Unlock()
+	for tag := range cacheKeyTags[key] {
+		if keys := cacheTags[tag]; keys != nil {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(cacheTags, tag)
+			}
+		}
+	}
+	delete(cacheKeyTags, key)
+}
+
+// ClearCacheTags 清空进程内标签索引 (由 ClearDataCache 调用)
+func ClearCacheTags() {
+	cacheTagsMu.Lock()
+	cacheTags = make(map[string]map[string]struct{})
+	cacheKeyTags = make(map[string]map[string]struct{})
+	cacheTagsMu.Unlock()
+}
@@ -0,0 +1,121 @@
+// chapter_split.go
+// 章节切分
+// 按标题正则将整本 TXT 文本切分为分卷与章节
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 默认标题匹配规则
+var (
+	DefaultVolumePatterns = []string{
+		`^第[零〇一二两三四五六七八九十百千万0-9０-９]+[卷部集](\s.*|$)`,
+	}
+	DefaultChapterPatterns = []string{
+		`^第[零〇一二两三四五六七八九十百千万0-9０-９]+[章节回].*$`,
+		`^(?i:chapter)\s*[0-9]+\b.*$`,
+		`^(序章|楔子|引子|序言|前言|尾声|后记|番外).*$`,
+	}
+)
+
+// maxHeadingLength 标题最大长度 (字符)，超过视为正文
+const maxHeadingLength = 50
+
+// SplitChapter 切分出的章节
+type SplitChapter struct {
+	Title    string
+	Content  string // 已去除标题的正文，换行统一为 \n
+	IsVolume bool   // 分卷 (chaptertype=1，无正文)
+}
+
+// ChapterSplitter 章节切分器
+type ChapterSplitter struct {
+	volumeRes  []*regexp.Regexp
+	chapterRes []*regexp.Regexp
+}
+
+// NewChapterSplitter 根据分卷及章节标题正则创建切分器，为空时使用默认规则
+func NewChapterSplitter(volumePatterns, chapterPatterns []string) (*ChapterSplitter, error) {
+	if len(volumePatterns) == 0 {
+		volumePatterns = DefaultVolumePatterns
+	}
+	if len(chapterPatterns) == 0 {
+		chapterPatterns = DefaultChapterPatterns
+	}
+	s := &ChapterSplitter{}
+	for _, p := range volumePatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		s.volumeRes = append(s.volumeRes, re)
+	}
+	for _, p := range chapterPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		s.chapterRes = append(s.chapterRes, re)
+	}
+	return s, nil
+}
+
+// matchAny 判断是否匹配任一正则
+func matchAny(res []*regexp.Regexp, line string) bool {
+	for _, re := range res {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// Split 切分文本
+// 第一个标题之前的非空内容作为 "前言" 章节，分卷标题与首个章节之间的内容作为 "卷首语" 章节
+func (s *ChapterSplitter) Split(text string) []SplitChapter {
+	var result []SplitChapter
+	var current *SplitChapter
+	var body strings.Builder
+
+	flush := func() {
+		content := strings.Trim(body.String(), "\n")
+		body.Reset()
+		if current != nil {
+			current.Content = content
+			result = append(result, *current)
+			return
+		}
+		if strings.TrimSpace(content) == "" {
+			return
+		}
+		title := "前言"
+		if len(result) > 0 {
+			title = "卷首语"
+		}
+		result = append(result, SplitChapter{Title: title, Content: content})
+	}
+
+	for _, line := range strings.Split(normalizeNewlines(text), "\n") {
+		trimmed := strings.TrimSpace(strings.TrimLeft(line, "\u3000\ufeff"))
+		if trimmed != "" && utf8.RuneCountInString(trimmed) <= maxHeadingLength {
+			if matchAny(s.volumeRes, trimmed) {
+				flush()
+				result = append(result, SplitChapter{Title: trimmed, IsVolume: true})
+				current = nil
+				continue
+			}
+			if matchAny(s.chapterRes, trimmed) {
+				flush()
+				current = &SplitChapter{Title: trimmed}
+				continue
+			}
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	flush()
+	return result
+}
@@ -0,0 +1,72 @@
+// content_cache.go
+// 章节内容缓存
+// 基于 LRU 的进程内章节 HTML 缓存，避免每次阅读都重新读取文件、转码及排版
+package utils
+
+import (
+	"bookweb/config"
+	"fmt"
+)
+
+// DefaultContentCacheSize 默认章节内容缓存容量 (MB)
+const DefaultContentCacheSize = 64
+
+var contentCache = NewLRUCache(DefaultContentCacheSize << 20)
+
+// contentCacheKey 章节内容缓存键 {articleID}:{chapterID}
+func contentCacheKey(articleID, chapterID int) string {
+	return fmt.Sprintf("%d:%d", articleID, chapterID)
+}
+
+// contentCacheCapacity 根据配置计算缓存容量 (字节)
+func contentCacheCapacity() int64 {
+	size := DefaultContentCacheSize
+	if cfg := config.GetGlobalConfig(); cfg != nil && cfg.Cache.ContentCacheSize != 0 {
+		size = cfg.Cache.ContentCacheSize
+	}
+	if size < 0 {
+		return 0
+	}
+	return int64(size) << 20
+}
+
+// GetChapterContentCached 带 LRU 缓存的章节内容读取
+// 读取失败的结果不缓存
+func GetChapterContentCached(articleID, chapterID, chapterOrder int) (string, error) {
+	// 配置可能被热重载，每次读取时同步容量
+	if capacity := contentCacheCapacity(); capacity != contentCache.Stats().Capacity {
+		contentCache.SetCapacity(capacity)
+	}
+
+	key := contentCacheKey(articleID, chapterID)
+	if content, ok := contentCache.Get(key); ok {
+		return content, nil
+	}
+
+	content, err := GetChapterFileContent(articleID, chapterID, chapterOrder)
+	if err != nil {
+		return content, err
+	}
+	contentCache.Set(key, content, 0)
+	return content, nil
+}
+
+// InvalidateChapterContent 使单个章节内容缓存失效
+func InvalidateChapterContent(articleID, chapterID int) {
+	contentCache.Delete(contentCacheKey(articleID, chapterID))
+}
+
+// InvalidateArticleContent 使整本小说的章节内容缓存失效
+func InvalidateArticleContent(articleID int) {
+	contentCache.DeletePrefix(fmt.Sprintf("%d:", articleID))
+}
+
+// ClearContentCache 清空章节内容缓存
+func ClearContentCache() {
+	contentCache.Clear()
+}
+
+// GetContentCacheStats 获取章节内容缓存统计
+func GetContentCacheStats() LRUStats {
+	return contentCache.Stats()
+}
@@ -0,0 +1,138 @@
+// data_cache.go
+// 两级数据缓存
+// L1 为进程内 LRU 缓存 (始终启用)，L2 为 Redis (可选)，供 DAO 层缓存查询结果
+package utils
+
+import (
+	"bookweb/config"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDataCacheSize 默认 L1 数据缓存容量 (MB)
+const DefaultDataCacheSize = 32
+
+// DataCacheL1MaxTTL 启用 Redis 时 L1 的最长缓存时间
+// 多实例部署时其它进程的失效操作只能清理 Redis，以此限制 L1 的不一致时间
+const DataCacheL1MaxTTL = 30 * time.Second
+
+var (
+	dataCache = NewLRUCache(DefaultDataCacheSize << 20)
+
+	// L2 (Redis) 命中统计，仅在 L1 未命中时计数
+	l2Hits   uint64
+	l2Misses uint64
+)
+
+// DataCacheStats 两级缓存统计信息
+type DataCacheStats struct {
+	L1        LRUStats
+	L2Enabled bool
+	L2Hits    uint64
+	L2Misses  uint64
+}
+
+// L2HitRate L2 命中率 (百分比)
+func (s DataCacheStats) L2HitRate() float64 {
+	total := s.L2Hits + s.L2Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.L2Hits) * 100 / float64(total)
+}
+
+// HitRate 总命中率 (百分比)，任一级命中均计为命中
+func (s DataCacheStats) HitRate() float64 {
+	total := s.L1.Hits + s.L1.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.L1.Hits+s.L2Hits) * 100 / float64(total)
+}
+
+// dataCacheCapacity 根据配置计算 L1 容量 (字节)
+func dataCacheCapacity() int64 {
+	size := DefaultDataCacheSize
+	if cfg := config.GetGlobalConfig(); cfg != nil && cfg.Cache.DataCacheSize != 0 {
+		size = cfg.Cache.DataCacheSize
+	}
+	if size < 0 {
+		return 0
+	}
+	return int64(size) << 20
+}
+
+// l1TTL 计算 L1 过期时间：未启用 Redis 时与调用方 TTL 一致，否则不超过 DataCacheL1MaxTTL
+func l1TTL(ttl time.Duration) time.Duration {
+	if IsRedisEnabled() && (ttl <= 0 || ttl > DataCacheL1MaxTTL) {
+		return DataCacheL1MaxTTL
+	}
+	return ttl
+}
+
+// DataCacheGet 依次从 L1、L2 获取缓存，L2 命中时回填 L1
+func DataCacheGet(key string) (string, bool) {
+	// 配置可能被热重载，每次读取时同步容量
+	if capacity := dataCacheCapacity(); capacity != dataCache.Stats().Capacity {
+		dataCache.SetCapacity(capacity)
+	}
+
+	if value, ok := dataCache.Get(key); ok {
+		return value, true
+	}
+	if !IsRedisEnabled() {
+		return "", false
+	}
+
+	value, err := CacheGet(key)
+	if err != nil || value == "" {
+		atomic.AddUint64(&l2Misses, 1)
+		return "", false
+	}
+	atomic.AddUint64(&l2Hits, 1)
+	dataCache.Set(key, value, DataCacheL1MaxTTL)
+	return value, true
+}
+
+// DataCacheSet 同时写入 L1 及 L2
+func DataCacheSet(key, value string, ttl time.Duration) {
+	dataCache.Set(key, value, l1TTL(ttl))
+	if IsRedisEnabled() {
+		CacheSet(key, value, ttl)
+	}
+}
+
+// DataCacheDel 从两级缓存中删除
+func DataCacheDel(keys ...string) {
+	for _, key := range keys {
+		dataCache.Delete(key)
+	}
+	if IsRedisEnabled() {
+		CacheDel(keys...)
+	}
+}
+
+// DataCacheDelPrefix 删除 L1 中指定前缀的键 (L2 由 CacheDelPattern 清理)，返回删除数量
+func DataCacheDelPrefix(prefixes ...string) int {
+	deleted := 0
+	for _, prefix := range prefixes {
+		deleted += dataCache.DeletePrefix(prefix)
+	}
+	return deleted
+}
+
+// ClearDataCache 清空 L1 数据缓存 (L2 由 CacheDelPattern 清理)
+func ClearDataCache() {
+	dataCache.Clear()
+	ClearCacheTags()
+}
+
+// GetDataCacheStats 获取两级缓存统计
+func GetDataCacheStats() DataCacheStats {
+	return DataCacheStats{
+		L1:        dataCache.Stats(),
+		L2Enabled: IsRedisEnabled(),
+		L2Hits:    atomic.LoadUint64(&l2Hits),
+		L2Misses:  atomic.LoadUint64(&l2Misses),
+	}
+}
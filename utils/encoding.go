@@ -0,0 +1,190 @@
+// encoding.go
+// 文本编码检测
+// 识别章节文本的编码 (UTF-8 BOM / UTF-16 BOM / UTF-8 / GBK / GB18030) 并统一转换为 UTF-8
+package utils
+
+import (
+	"bookweb/config"
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// 文本编码名称
+const (
+	EncodingASCII   = "ascii"
+	EncodingUTF8    = "utf-8"
+	EncodingUTF8BOM = "utf-8-bom"
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+	EncodingGBK     = "gbk"
+	EncodingGB18030 = "gb18030"
+	EncodingUnknown = "unknown"
+)
+
+// gbkMaxInvalidRatio GBK 检测允许的非法字节序列比例 (兼容个别损坏的旧文件)
+const gbkMaxInvalidRatio = 0.01
+
+// DefaultTextEncoding 获取站点默认文本编码，检测无法判定时使用
+func DefaultTextEncoding() string {
+	if cfg := config.GetGlobalConfig(); cfg != nil {
+		if enc := NormalizeEncoding(cfg.Storage.Encoding); enc != "" {
+			return enc
+		}
+	}
+	return EncodingGBK
+}
+
+// NormalizeEncoding 规范化编码名称，无法识别时返回空字符串
+func NormalizeEncoding(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "utf-8", "utf8":
+		return EncodingUTF8
+	case "utf-16le", "utf16le":
+		return EncodingUTF16LE
+	case "utf-16be", "utf16be":
+		return EncodingUTF16BE
+	case "gbk", "gb2312", "cp936":
+		return EncodingGBK
+	case "gb18030":
+		return EncodingGB18030
+	}
+	return ""
+}
+
+// DetectEncoding 检测文本编码
+// 依次判断: UTF-8 BOM、UTF-16 BOM、纯 ASCII、UTF-8 合法性、GBK/GB18030 双字节/四字节序列
+// 均无法判定时返回 fallback
+func DetectEncoding(data []byte, fallback string) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return EncodingUTF8BOM
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return EncodingUTF16BE
+	}
+
+	ascii := true
+	for _, b := range data {
+		if b >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return EncodingASCII
+	}
+	if utf8.Valid(trimIncompleteUTF8(data)) {
+		return EncodingUTF8
+	}
+	if enc := detectGBK(data); enc != "" {
+		return enc
+	}
+	return fallback
+}
+
+// trimIncompleteUTF8 去掉末尾被截断的不完整 UTF-8 字符，避免按字节截取的 UTF-8 文本被误判为 GBK
+func trimIncompleteUTF8(data []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		b := data[len(data)-i]
+		if b < utf8.RuneSelf {
+			return data
+		}
+		if utf8.RuneStart(b) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return data[:len(data)-i]
+			}
+			return data
+		}
+	}
+	return data
+}
+
+// detectGBK 按 GBK/GB18030 编码规则扫描字节流
+// 合法序列比例足够高时返回 gbk (含四字节序列时返回 gb18030)，否则返回空字符串
+func detectGBK(data []byte) string {
+	var valid, invalid int
+	fourByte := false
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b < 0x80 {
+			i++
+			continue
+		}
+		// 末尾被截断的半个字符不计入
+		if i+1 >= len(data) {
+			break
+		}
+		b2 := data[i+1]
+		switch {
+		case b >= 0x81 && b <= 0xFE && b2 >= 0x40 && b2 <= 0xFE && b2 != 0x7F:
+			valid++
+			i += 2
+		case b >= 0x81 && b <= 0xFE && b2 >= 0x30 && b2 <= 0x39 && i+3 < len(data) &&
+			data[i+2] >= 0x81 && data[i+2] <= 0xFE && data[i+3] >= 0x30 && data[i+3] <= 0x39:
+			valid++
+			fourByte = true
+			i += 4
+		default:
+			invalid++
+			i++
+		}
+	}
+	if valid == 0 || float64(invalid) > float64(valid+invalid)*gbkMaxInvalidRatio {
+		return ""
+	}
+	if fourByte {
+		return EncodingGB18030
+	}
+	return EncodingGBK
+}
+
+// textDecoder 获取编码对应的解码器，UTF-8 / ASCII 返回 nil
+func textDecoder(enc string) encoding.Encoding {
+	switch enc {
+	case EncodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case EncodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case EncodingGBK:
+		return simplifiedchinese.GBK
+	case EncodingGB18030:
+		return simplifiedchinese.GB18030
+	}
+	return nil
+}
+
+// DecodeText 按指定编码转换为 UTF-8
+func DecodeText(data []byte, enc string) ([]byte, error) {
+	if enc == EncodingUTF8BOM {
+		return data[3:], nil
+	}
+	decoder := textDecoder(enc)
+	if decoder == nil {
+		return data, nil
+	}
+	return io.ReadAll(transform.NewReader(bytes.NewReader(data), decoder.NewDecoder()))
+}
+
+// EncodeText 将 UTF-8 文本转换为指定编码
+func EncodeText(text []byte, enc string) ([]byte, error) {
+	encoder := textDecoder(enc)
+	if encoder == nil {
+		return text, nil
+	}
+	return io.ReadAll(transform.NewReader(bytes.NewReader(text), encoder.NewEncoder()))
+}
+
+// ToUtf8 自动检测编码并转换为 UTF-8，返回转换结果及检测到的编码
+func ToUtf8(data []byte) ([]byte, string, error) {
+	enc := DetectEncoding(data, DefaultTextEncoding())
+	out, err := DecodeText(data, enc)
+	return out, enc, err
+}
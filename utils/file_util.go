@@ -4,51 +4,47 @@
 package utils
 
 import (
-	"bookweb/config"
-	"bytes"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
-
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"golang.org/x/text/transform"
 )
 
-// GetChapterFileContent 读取章节内容
-// path format: /files/article/txt/{articleID/1000}/{articleID}/{chapterOrder_or_chapterID}.txt
-func GetChapterFileContent(articleID, chapterID, chapterOrder int) (string, error) {
-
+// ChapterFilePath 获取章节文本在存储中的相对路径
+// 规则: article/txt/{articleID/1000}/{articleID}/{chapterOrder_or_chapterID}.txt
+func ChapterFilePath(articleID, chapterID, chapterOrder int) string {
 	fileName := chapterID
 	if chapterOrder > 0 {
 		fileName = chapterOrder
 	}
-
-	// Construct the relative path (rules remain unchanged)
 	subDir1 := articleID / 1000
-	relPath := fmt.Sprintf("article/txt/%d/%d/%d.txt", subDir1, articleID, fileName)
+	return fmt.Sprintf("article/txt/%d/%d/%d.txt", subDir1, articleID, fileName)
+}
 
-	// Read content from configured storage
-	data, err := GetFileContent(relPath)
+// GetChapterFileContent 读取章节内容
+// path format: /files/article/txt/{articleID/1000}/{articleID}/{chapterOrder_or_chapterID}.txt
+func GetChapterFileContent(articleID, chapterID, chapterOrder int) (string, error) {
+	// 1. Read filtered plain text
+	content, err := GetChapterFilteredText(articleID, chapterID, chapterOrder)
 	if err != nil {
 		return "章节内容不存在", err
 	}
 
-	// 1. GBK to UTF-8 Conversion
-	utf8Data, err := GbkToUtf8(data)
+	// 2. Format plain text to HTML
+	return FormatChapterHTML(content), nil
+}
+
+// GetChapterFilteredText 读取章节纯文本并应用过滤规则 (换行统一为 \n)
+func GetChapterFilteredText(articleID, chapterID, chapterOrder int) (string, error) {
+	content, err := GetChapterFileText(articleID, chapterID, chapterOrder)
 	if err != nil {
-		// If conversion fails, fallback to original data string
-		return string(data), nil
+		return "", err
 	}
+	return ApplyTextFilter(normalizeNewlines(content)), nil
+}
 
-	// 2. Format plain text to HTML
-	content := string(utf8Data)
-	content = strings.ReplaceAll(content, "\r\n", "\n")
-	content = strings.ReplaceAll(content, "\r", "\n")
-	paras := strings.Split(content, "\n")
+// FormatChapterHTML 将纯文本按行排版为 <p> 段落
+func FormatChapterHTML(content string) string {
+	paras := strings.Split(normalizeNewlines(content), "\n")
 	var htmlContent strings.Builder
 	for _, p := range paras {
 		p = strings.TrimSpace(p)
@@ -58,81 +54,81 @@ func GetChapterFileContent(articleID, chapterID, chapterOrder int) (string, erro
 			htmlContent.WriteString("</p>")
 		}
 	}
+	return htmlContent.String()
+}
 
-	return htmlContent.String(), nil
+// normalizeNewlines 统一换行符为 \n
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
 }
 
-// GetFileContent 根据配置从不同存储后端读取文件内容
-func GetFileContent(relPath string) ([]byte, error) {
-	cfg := config.GetGlobalConfig()
-	// 默认使用本地存储 (NFS 模式通常也映射为本地路径)
-
-	var storageType string
-	if cfg == nil {
-		storageType = "local"
-	} else {
-		storageType = cfg.Storage.Type
+// GetChapterFileText 读取章节原始文本 (自动检测编码并转换为 UTF-8，未排版)
+func GetChapterFileText(articleID, chapterID, chapterOrder int) (string, error) {
+	data, err := GetFileContent(ChapterFilePath(articleID, chapterID, chapterOrder))
+	if err != nil {
+		return "", err
 	}
-
-	switch storageType {
-	case "oss":
-		return readOssData(cfg, relPath)
-	case "local":
-		fullPath := filepath.Join(cfg.Storage.Local.Path, relPath)
-		return os.ReadFile(fullPath)
-	default:
-		return nil, fmt.Errorf("unsupported storage type: %s", storageType)
+	utf8Data, _, err := ToUtf8(data)
+	if err != nil {
+		// If conversion fails, fallback to original data string
+		return string(data), nil
 	}
+	return string(utf8Data), nil
 }
 
-// readOssData 从对象存储读取数据
-func readOssData(cfg *config.AppConfig, relPath string) ([]byte, error) {
-	// 如果设置了自定义域名，优先使用域名拼接
-	url := ""
-	if cfg.Storage.Oss.Domain != "" {
-		url = fmt.Sprintf("%s/%s", strings.TrimRight(cfg.Storage.Oss.Domain, "/"), relPath)
-	} else {
-		// 默认模式: https://{bucket}.{endpoint}/{path}
-		endpoint := strings.TrimLeft(cfg.Storage.Oss.Endpoint, "https://")
-		endpoint = strings.TrimLeft(endpoint, "http://")
-		url = fmt.Sprintf("https://%s.%s/%s", cfg.Storage.Oss.Bucket, endpoint, relPath)
+// SaveChapterFileText 保存章节文本
+// 沿用原文件的编码写回 (新文件或纯 ASCII 时使用站点默认编码)，
+// 文本含目标编码无法表示的字符时改用 UTF-8 写入
+func SaveChapterFileText(articleID, chapterID, chapterOrder int, text string) error {
+	storage, err := GetStorage()
+	if err != nil {
+		return err
 	}
+	path := ChapterFilePath(articleID, chapterID, chapterOrder)
 
-	// 简单实现：通过 HTTP GET 获取 (适用于公共读或已授权路径)
-	// 对于私有存储，后续可在此处引入各个厂商的 SDK (如阿里云 OSS, 腾讯云 COS, AWS S3 等)
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
+	enc := DefaultTextEncoding()
+	if old, err := storage.Read(path); err == nil {
+		if detected := DetectEncoding(old, enc); detected != EncodingASCII {
+			enc = detected
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("oss storage read failed, status: %d", resp.StatusCode)
+	data, err := encodeChapterText(text, enc)
+	if err != nil {
+		if data, err = encodeChapterText(text, EncodingUTF8); err != nil {
+			return err
+		}
 	}
+	return storage.Write(path, data)
+}
 
-	return io.ReadAll(resp.Body)
+// encodeChapterText 按指定编码编码章节文本，UTF-8 BOM 文件保留 BOM
+func encodeChapterText(text, enc string) ([]byte, error) {
+	if enc == EncodingUTF8BOM {
+		return append([]byte{0xEF, 0xBB, 0xBF}, text...), nil
+	}
+	return EncodeText([]byte(text), enc)
 }
 
-// GbkToUtf8 转换 GBK 字节流为 UTF-8
-func GbkToUtf8(s []byte) ([]byte, error) {
-	reader := transform.NewReader(bytes.NewReader(s), simplifiedchinese.GBK.NewDecoder())
-	d, err := io.ReadAll(reader)
+// GetFileContent 从当前配置的存储后端读取文件内容
+func GetFileContent(relPath string) ([]byte, error) {
+	storage, err := GetStorage()
 	if err != nil {
 		return nil, err
 	}
-	return d, nil
+	return storage.Read(relPath)
 }
 
 // GetCoverPath 获取小说封面图片解析路径 (前端调用)
 func GetCoverPath(articleID int) string {
-	cfg := config.GetGlobalConfig()
-	// 如果使用 OSS 且配置了域名，直接返回 OSS 上的 URL
-	if cfg != nil && cfg.Storage.Type == "oss" && cfg.Storage.Oss.Domain != "" {
-		subDir := articleID / 1000
-		return fmt.Sprintf("%s/article/image/%d/%d/%ds.jpg",
-			strings.TrimRight(cfg.Storage.Oss.Domain, "/"), subDir, articleID, articleID)
+	// 如果存储后端提供直链 (如 OSS 配置了域名)，直接返回
+	if storage, err := GetStorage(); err == nil {
+		if url := storage.PublicURL(GetPhysicalCoverPath(articleID)); url != "" {
+			return url
+		}
 	}
-	// 默认本地模式，返回内部路由路径
+	// 默认返回内部路由路径
 	return fmt.Sprintf("/img/%d.jpg", EncodeID(articleID))
 }
 
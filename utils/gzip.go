@@ -36,6 +36,16 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+// Flush 将已压缩的数据立即发送给客户端 (用于流式响应)
+func (w *gzipResponseWriter) Flush() {
+	if w.gzWriter != nil {
+		w.gzWriter.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (w *gzipResponseWriter) Close() {
 	if w.gzWriter != nil {
 		w.gzWriter.Close()
@@ -0,0 +1,97 @@
+// http_cache.go
+// HTTP 条件请求
+// 生成 ETag/Last-Modified 并处理 If-None-Match/If-Modified-Since，内容未变化时返回 304
+package utils
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// etagGeneration 后台手动清空整页缓存的次数，使本进程已发出的 ETag 失效
+var etagGeneration atomic.Int64
+
+// BumpETagVersion 手动使页面 ETag 失效 (仅影响本进程，模板及过滤规则变化已由 RenderVersion 自动反映)
+func BumpETagVersion() {
+	etagGeneration.Add(1)
+}
+
+// RenderVersion 页面渲染版本，由模板文件摘要及章节过滤规则版本确定，用于页面 ETag 及静态页
+// 多实例及重启后保持一致，重载模板 (InitTemplates) 或过滤规则 (ReloadTextFilter) 后内容有变化时随之改变
+func RenderVersion() string {
+	return TemplateVersion() + "-" + TextFilterVersion()
+}
+
+// PageETag 根据页面标识 (路由、ID、更新时间、终端等) 生成弱 ETag
+// 页面经 GZIP 压缩后字节不同，因此使用弱校验
+func PageETag(parts ...string) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s-%d", RenderVersion(), etagGeneration.Load())
+	for _, p := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(p))
+	}
+	return fmt.Sprintf(`W/"%x"`, h.Sum64())
+}
+
+// FileETag 根据文件修改时间及大小生成弱 ETag
+func FileETag(modTime time.Time, size int64) string {
+	return fmt.Sprintf(`W/"%x-%x"`, modTime.Unix(), size)
+}
+
+// CheckNotModified 写入 ETag/Last-Modified 响应头，请求条件满足时输出 304 并返回 true
+// 同时设置 Cache-Control: no-cache，要求客户端每次重新验证，避免按 Last-Modified 启发式缓存旧页面
+func CheckNotModified(w http.ResponseWriter, r *http.Request, etag string, modTime time.Time) bool {
+	h := w.Header()
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+	if !modTime.IsZero() {
+		h.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	h.Set("Cache-Control", "no-cache")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if !isNotModified(r, etag, modTime) {
+		return false
+	}
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// isNotModified 判断条件请求是否命中；有 If-None-Match 时忽略 If-Modified-Since (RFC 7232)
+func isNotModified(r *http.Request, etag string, modTime time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && etagMatch(inm, etag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || modTime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// HTTP 时间精度为秒
+	return !modTime.Truncate(time.Second).After(t)
+}
+
+// etagMatch 弱比较 If-None-Match 列表中是否包含 etag
+func etagMatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
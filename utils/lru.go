@@ -0,0 +1,203 @@
+// lru.go
+// LRU 缓存
+// 按字节容量限制的进程内 LRU 缓存，支持可选过期时间及命中统计
+package utils
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LRUStats LRU 缓存统计信息
+type LRUStats struct {
+	Hits      uint64 // 命中次数
+	Misses    uint64 // 未命中次数
+	Evictions uint64 // 淘汰次数
+	Items     int    // 当前条目数
+	Bytes     int64  // 当前占用字节
+	Capacity  int64  // 容量上限 (字节)
+}
+
+// HitRate 命中率 (百分比)
+func (s LRUStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) * 100 / float64(total)
+}
+
+// LRUCache 按字节计量的 LRU 缓存
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int64
+	used     int64
+	ll       *list.List
+	items    map[string]*list.Element
+	onRemove func(key string) // 条目被删除、淘汰或过期时调用 (持有锁，不得回调缓存自身)
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+type lruEntry struct {
+	key      string
+	value    string
+	size     int64
+	expireAt time.Time // 零值表示永不过期
+}
+
+// NewLRUCache 创建容量为 capacity 字节的 LRU 缓存，capacity <= 0 表示禁用
+func NewLRUCache(capacity int64) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// SetRemoveHook 设置条目被删除、淘汰、过期或因过大未能写入时的回调 (Clear 不触发)
+func (c *LRUCache) SetRemoveHook(fn func(key string)) {
+	c.mu.Lock()
+	c.onRemove = fn
+	c.mu.Unlock()
+}
+
+// entrySize 估算条目占用字节 (键 + 值)
+func entrySize(key, value string) int64 {
+	return int64(len(key) + len(value))
+}
+
+// Get 获取缓存值
+func (c *LRUCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		if e.expireAt.IsZero() || time.Now().Before(e.expireAt) {
+			c.ll.MoveToFront(el)
+			atomic.AddUint64(&c.hits, 1)
+			return e.value, true
+		}
+		c.removeElement(el)
+	}
+	atomic.AddUint64(&c.misses, 1)
+	return "", false
+}
+
+// Set 写入缓存值，ttl <= 0 表示永不过期
+// 单个值超过容量时不缓存
+func (c *LRUCache) Set(key, value string, ttl time.Duration) {
+	size := entrySize(key, value)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.capacity <= 0 || size > c.capacity {
+		// 旧值 (如有) 已失效，一并删除
+		if el, ok := c.items[key]; ok {
+			c.removeElement(el)
+		} else if c.onRemove != nil {
+			c.onRemove(key)
+		}
+		return
+	}
+
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		c.used += size - e.size
+		e.value = value
+		e.size = size
+		e.expireAt = expireAt
+		c.ll.MoveToFront(el)
+	} else {
+		el := c.ll.PushFront(&lruEntry{key: key, value: value, size: size, expireAt: expireAt})
+		c.items[key] = el
+		c.used += size
+	}
+	c.evict()
+}
+
+// Delete 删除缓存值
+func (c *LRUCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// DeletePrefix 删除指定前缀的所有缓存值，返回删除数量
+func (c *LRUCache) DeletePrefix(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := 0
+	for key, el := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.removeElement(el)
+			count++
+		}
+	}
+	return count
+}
+
+// Clear 清空缓存
+func (c *LRUCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.used = 0
+}
+
+// SetCapacity 调整容量，超出部分立即淘汰
+func (c *LRUCache) SetCapacity(capacity int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capacity = capacity
+	c.evict()
+}
+
+// Stats 获取统计信息
+func (c *LRUCache) Stats() LRUStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return LRUStats{
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: atomic.LoadUint64(&c.evictions),
+		Items:     len(c.items),
+		Bytes:     c.used,
+		Capacity:  c.capacity,
+	}
+}
+
+// evict 淘汰最久未使用的条目直到不超过容量 (调用方需持有锁)
+func (c *LRUCache) evict() {
+	for c.used > c.capacity && c.ll.Len() > 0 {
+		c.removeElement(c.ll.Back())
+		atomic.AddUint64(&c.evictions, 1)
+	}
+}
+
+// removeElement 移除条目 (调用方需持有锁)
+func (c *LRUCache) removeElement(el *list.Element) {
+	e := el.Value.(*lruEntry)
+	c.ll.Remove(el)
+	delete(c.items, e.key)
+	c.used -= e.size
+	if c.onRemove != nil {
+		c.onRemove(e.key)
+	}
+}
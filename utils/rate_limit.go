@@ -0,0 +1,173 @@
+// rate_limit.go
+// 访问频率限制
+// 基于固定时间窗口的进程内计数器，用于按 IP 等维度限制请求频率
+package utils
+
+import (
+	"bookweb/config"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter 固定窗口频率限制器
+type RateLimiter struct {
+	mu        sync.Mutex
+	window    time.Duration
+	counters  map[string]*rateCounter
+	lastSweep time.Time
+}
+
+type rateCounter struct {
+	count   int
+	resetAt time.Time
+}
+
+// NewRateLimiter 创建窗口长度为 window 的频率限制器
+func NewRateLimiter(window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		window:    window,
+		counters:  make(map[string]*rateCounter),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow 判断 key 在当前窗口内是否未超过 limit 次，允许时计数加一
+// limit <= 0 表示不限制
+func (l *RateLimiter) Allow(key string, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	c, ok := l.counters[key]
+	if !ok || now.After(c.resetAt) {
+		c = &rateCounter{resetAt: now.Add(l.window)}
+		l.counters[key] = c
+	}
+	if c.count >= limit {
+		return false
+	}
+	c.count++
+	return true
+}
+
+// sweep 定期清理过期计数，防止内存无限增长 (调用方需持有锁)
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for key, c := range l.counters {
+		if now.After(c.resetAt) {
+			delete(l.counters, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// GetClientIP 获取客户端真实 IP
+// 仅当直连地址 (RemoteAddr) 属于 server.trusted_proxies 时才读取代理头：
+// 从 X-Forwarded-For 右侧开始跳过受信任代理，取第一个不受信任的地址，无 X-Forwarded-For 时读取 X-Real-IP；
+// 否则直接使用 RemoteAddr (去掉端口)，避免客户端伪造请求头
+func GetClientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+
+	proxies := trustedProxyNets()
+	if len(proxies) == 0 || !ipInNets(remote, proxies) {
+		return remote
+	}
+
+	if xffs := r.Header.Values("X-Forwarded-For"); len(xffs) > 0 {
+		// 链路上全部为受信任代理时取最左侧的有效地址
+		client := remote
+		hops := strings.Split(strings.Join(xffs, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				// 无法解析的地址视为链路被篡改，不再继续向左信任
+				break
+			}
+			if !ipInNets(hop, proxies) {
+				return hop
+			}
+			client = hop
+		}
+		return client
+	}
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(xri) != nil {
+		return xri
+	}
+	return remote
+}
+
+var (
+	trustedProxyMu   sync.Mutex
+	trustedProxySrc  []string
+	trustedProxyList []*net.IPNet
+)
+
+// trustedProxyNets 返回解析后的受信任代理网段，配置未变化时复用上次解析结果
+func trustedProxyNets() []*net.IPNet {
+	cfg := config.GetGlobalConfig()
+	if cfg == nil || len(cfg.Server.TrustedProxies) == 0 {
+		return nil
+	}
+	src := cfg.Server.TrustedProxies
+
+	trustedProxyMu.Lock()
+	defer trustedProxyMu.Unlock()
+	if slices.Equal(src, trustedProxySrc) {
+		return trustedProxyList
+	}
+
+	nets := make([]*net.IPNet, 0, len(src))
+	for _, item := range src {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			if ip := net.ParseIP(item); ip != nil {
+				bits := 128
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+		if _, n, err := net.ParseCIDR(item); err == nil {
+			nets = append(nets, n)
+		} else {
+			LogWarn("RateLimit", "Invalid trusted proxy %q ignored", item)
+		}
+	}
+	trustedProxySrc = slices.Clone(src)
+	trustedProxyList = nets
+	return nets
+}
+
+// ipInNets 判断 IP 是否属于任一网段
+func ipInNets(s string, nets []*net.IPNet) bool {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
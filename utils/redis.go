@@ -7,92 +7,319 @@ import (
 	"bookweb/config"
 	"context"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+var redisCtx = context.Background()
+
+// redisConn 一个 Redis 连接及其键前缀、健康状态，重新初始化时整体原子替换
+type redisConn struct {
+	client redis.UniversalClient
+	prefix string      // 键前缀，多个站点共用同一 Redis 库时用于隔离
+	up     atomic.Bool // 最近一次健康检查是否成功
+	stop   chan struct{}
+}
+
+// key 为逻辑键加上站点前缀
+func (c *redisConn) key(key string) string {
+	return c.prefix + key
+}
+
 var (
-	RedisClient *redis.Client
-	redisCtx    = context.Background()
+	redisCurrent atomic.Pointer[redisConn]
+	redisInitMu  sync.Mutex // 串行化 InitRedis
 )
 
-// InitRedis 初始化 Redis 连接
+// activeRedis 获取当前可用的连接，未启用或不可用时返回 nil
+// 调用方在一次操作中只读取一次，避免与 InitRedis 并发时前后使用不同的连接
+func activeRedis() *redisConn {
+	if c := redisCurrent.Load(); c != nil && c.up.Load() {
+		return c
+	}
+	return nil
+}
+
+// errRedisDisabled Redis 未启用或不可用
+var errRedisDisabled = fmt.Errorf("redis not enabled")
+
+// redisScanBatch SCAN 每批返回及删除的键数量
+const redisScanBatch = 500
+
+// redisHealthInterval Redis 健康检查间隔，连接断开期间按此间隔重试
+const redisHealthInterval = 5 * time.Second
+
+// Redis 部署模式
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
+// RedisPrefix 当前站点的 Redis 键前缀
+func RedisPrefix() string {
+	if c := redisCurrent.Load(); c != nil {
+		return c.prefix
+	}
+	return ""
+}
+
+// RedisKey 为逻辑键加上站点前缀
+func RedisKey(key string) string {
+	return RedisPrefix() + key
+}
+
+// InitRedis 初始化 Redis 连接 (单机 / Sentinel / Cluster)
+// 新连接创建并 PING 后才替换当前连接，旧连接随后关闭；并发请求始终使用完整的旧连接或新连接
+// 启动时连接失败不会禁用 Redis：客户端保留，由后台健康检查在恢复后自动重新启用
 func InitRedis(cfg *config.RedisConfig) error {
+	redisInitMu.Lock()
+	defer redisInitMu.Unlock()
+
 	if cfg == nil || !cfg.Enabled {
-		RedisClient = nil
+		closeRedis(redisCurrent.Swap(nil))
 		return nil
 	}
 
-	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
-	RedisClient = redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: cfg.Password,
-		DB:       cfg.DB,
-	})
-
-	// 测试连接
-	_, err := RedisClient.Ping(redisCtx).Result()
+	client, err := newRedisClient(cfg)
 	if err != nil {
-		RedisClient = nil
 		return err
 	}
-	return nil
+	conn := &redisConn{client: client, prefix: cfg.Prefix, stop: make(chan struct{})}
+
+	// 测试连接
+	err = client.Ping(redisCtx).Err()
+	conn.up.Store(err == nil)
+
+	closeRedis(redisCurrent.Swap(conn))
+	go monitorRedis(conn)
+	return err
+}
+
+// NewRedisClient 按配置创建独立的客户端 (如后台测试连接)，由调用方负责关闭，不影响当前连接
+func NewRedisClient(cfg *config.RedisConfig) (redis.UniversalClient, error) {
+	return newRedisClient(cfg)
+}
+
+// newRedisClient 按部署模式创建客户端
+func newRedisClient(cfg *config.RedisConfig) (redis.UniversalClient, error) {
+	opts := &redis.UniversalOptions{
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		DialTimeout:  time.Duration(cfg.DialTimeout) * time.Millisecond,
+		ReadTimeout:  time.Duration(cfg.ReadTimeout) * time.Millisecond,
+		WriteTimeout: time.Duration(cfg.WriteTimeout) * time.Millisecond,
+		PoolSize:     cfg.PoolSize,
+	}
+
+	switch strings.ToLower(cfg.Mode) {
+	case "", RedisModeSingle:
+		opts.Addrs = []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)}
+	case RedisModeSentinel:
+		if cfg.MasterName == "" || len(cfg.Addrs) == 0 {
+			return nil, fmt.Errorf("sentinel mode requires master_name and addrs")
+		}
+		opts.Addrs = cfg.Addrs
+		opts.MasterName = cfg.MasterName
+		opts.SentinelPassword = cfg.SentinelPassword
+	case RedisModeCluster:
+		if len(cfg.Addrs) == 0 {
+			return nil, fmt.Errorf("cluster mode requires addrs")
+		}
+		opts.Addrs = cfg.Addrs
+		opts.IsClusterMode = true
+	default:
+		return nil, fmt.Errorf("unknown redis mode: %s", cfg.Mode)
+	}
+	return redis.NewUniversalClient(opts), nil
+}
+
+// monitorRedis 定期 PING，连接断开时暂停使用 Redis (降级到 L1 / 数据库)，恢复后自动启用
+func monitorRedis(conn *redisConn) {
+	ticker := time.NewTicker(redisHealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := conn.client.Ping(redisCtx).Err()
+			up := err == nil
+			if conn.up.Swap(up) != up {
+				if up {
+					LogInfo("Redis", "Redis reconnected")
+				} else {
+					LogWarn("Redis", "Redis unavailable, falling back: %v", err)
+				}
+			}
+		case <-conn.stop:
+			return
+		}
+	}
+}
+
+// closeRedis 停止连接的健康检查并关闭客户端 (已从 redisCurrent 中移除的连接)
+func closeRedis(conn *redisConn) {
+	if conn == nil {
+		return
+	}
+	conn.up.Store(false)
+	close(conn.stop)
+	conn.client.Close()
 }
 
 // CacheGet 从缓存获取数据
 func CacheGet(key string) (string, error) {
-	if RedisClient == nil {
-		return "", fmt.Errorf("redis not enabled")
+	c := activeRedis()
+	if c == nil {
+		return "", errRedisDisabled
 	}
-	return RedisClient.Get(redisCtx, key).Result()
+	return c.client.Get(redisCtx, c.key(key)).Result()
 }
 
 // CacheSet 设置缓存数据
 func CacheSet(key string, value interface{}, expiration time.Duration) error {
-	if RedisClient == nil {
-		return fmt.Errorf("redis not enabled")
+	c := activeRedis()
+	if c == nil {
+		return errRedisDisabled
 	}
-	return RedisClient.Set(redisCtx, key, value, expiration).Err()
+	return c.client.Set(redisCtx, c.key(key), value, expiration).Err()
 }
 
-// CacheDel 删除缓存
-func CacheDel(key string) error {
-	if RedisClient == nil {
-		return fmt.Errorf("redis not enabled")
+// CacheDel 删除缓存，支持一次删除多个键
+func CacheDel(keys ...string) error {
+	c := activeRedis()
+	if c == nil {
+		return errRedisDisabled
 	}
-	return RedisClient.Del(redisCtx, key).Err()
+	if len(keys) == 0 {
+		return nil
+	}
+	redisKeys := make([]string, len(keys))
+	for i, key := range keys {
+		redisKeys[i] = c.key(key)
+	}
+
+	// Cluster 模式下多个键可能分布在不同槽位 (CROSSSLOT)，逐个删除并由 Pipeline 按节点分发
+	if _, ok := c.client.(*redis.ClusterClient); ok && len(redisKeys) > 1 {
+		_, err := c.client.Pipelined(redisCtx, func(pipe redis.Pipeliner) error {
+			for _, key := range redisKeys {
+				pipe.Del(redisCtx, key)
+			}
+			return nil
+		})
+		return err
+	}
+	return c.client.Del(redisCtx, redisKeys...).Err()
 }
 
-// CacheFlush 清空所有缓存 (FLUSHDB)
-func CacheFlush() error {
-	if RedisClient == nil {
-		return fmt.Errorf("redis not enabled")
+// CacheScan 按模式 (不含前缀，如 page_cache:*) 遍历本站的键，fn 收到的是去掉前缀的逻辑键
+// 使用 SCAN 分批遍历，不阻塞 Redis；不会触及其它站点 (前缀不同) 的键
+// Cluster 模式下遍历所有主节点，fn 的调用已串行化
+func CacheScan(pattern string, fn func(key string) error) error {
+	c := activeRedis()
+	if c == nil {
+		return errRedisDisabled
+	}
+
+	var mu sync.Mutex
+	scanNode := func(ctx context.Context, node redis.Cmdable) error {
+		iter := node.Scan(ctx, 0, c.key(pattern), redisScanBatch).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			err := fn(strings.TrimPrefix(iter.Val(), c.prefix))
+			mu.Unlock()
+			if err != nil {
+				return err
+			}
+		}
+		return iter.Err()
 	}
-	return RedisClient.FlushDB(redisCtx).Err()
+
+	if cluster, ok := c.client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(redisCtx, func(ctx context.Context, node *redis.Client) error {
+			return scanNode(ctx, node)
+		})
+	}
+	return scanNode(redisCtx, c.client)
+}
+
+// CacheDelPattern 删除匹配模式的本站键，skip 返回 true 的键保留，返回删除数量
+func CacheDelPattern(pattern string, skip func(key string) bool) (int, error) {
+	var batch []string
+	deleted := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := CacheDel(batch...); err != nil {
+			return err
+		}
+		deleted += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err := CacheScan(pattern, func(key string) error {
+		if skip != nil && skip(key) {
+			return nil
+		}
+		batch = append(batch, key)
+		if len(batch) >= redisScanBatch {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, flush()
 }
 
 // CacheIncr 增加缓存计数
 func CacheIncr(key string) (int64, error) {
-	if RedisClient == nil {
-		return 0, fmt.Errorf("redis not enabled")
+	c := activeRedis()
+	if c == nil {
+		return 0, errRedisDisabled
+	}
+	return c.client.Incr(redisCtx, c.key(key)).Result()
+}
+
+// CacheIncrBy 增加缓存计数指定值
+func CacheIncrBy(key string, value int64) (int64, error) {
+	c := activeRedis()
+	if c == nil {
+		return 0, errRedisDisabled
+	}
+	return c.client.IncrBy(redisCtx, c.key(key), value).Result()
+}
+
+// CacheSetNX 键不存在时设置值及过期时间，返回是否设置成功
+func CacheSetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	c := activeRedis()
+	if c == nil {
+		return false, errRedisDisabled
 	}
-	return RedisClient.Incr(redisCtx, key).Result()
+	return c.client.SetNX(redisCtx, c.key(key), value, expiration).Result()
 }
 
-// CacheGetSet 设置新值并返回旧值
-func CacheGetSet(key string, value interface{}) (string, error) {
-	if RedisClient == nil {
-		return "", fmt.Errorf("redis not enabled")
+// CacheGetDel 读取并删除键 (GETDEL，需 Redis 6.2+)，键不存在时返回空字符串
+func CacheGetDel(key string) (string, error) {
+	c := activeRedis()
+	if c == nil {
+		return "", errRedisDisabled
 	}
-	val, err := RedisClient.GetSet(redisCtx, key, value).Result()
+	val, err := c.client.GetDel(redisCtx, c.key(key)).Result()
 	if err == redis.Nil {
 		return "", nil
 	}
 	return val, err
 }
 
-// IsRedisEnabled 检查 Redis 是否已启用
+// IsRedisEnabled 检查 Redis 是否已启用且当前可用
 func IsRedisEnabled() bool {
-	return RedisClient != nil
+	return activeRedis() != nil
 }
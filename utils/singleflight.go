@@ -0,0 +1,92 @@
+// singleflight.go
+// 请求合并
+// 同一键的并发加载只执行一次，其余调用等待并共享结果，防止缓存失效瞬间的回源风暴
+package utils
+
+import (
+	"fmt"
+	"sync"
+)
+
+// FlightGroup 请求合并组，零值可直接使用
+type FlightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg  sync.WaitGroup
+	val string
+	err error
+}
+
+// Do 执行 fn 并返回结果；同一键已有执行中的调用时等待其完成并共享结果
+func (g *FlightGroup) Do(key string, fn func() (string, error)) (string, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+	c := &flightCall{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// fn panic 时等待中的调用返回错误而非空结果，释放后继续向上抛出
+	defer func() {
+		r := recover()
+		if r != nil {
+			c.err = flightPanicError(key, r)
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+		if r != nil {
+			panic(r)
+		}
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err
+}
+
+// DoAsync 在后台执行 fn，同一键已有执行中的调用时直接返回
+func (g *FlightGroup) DoAsync(key string, fn func() (string, error)) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if _, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		return
+	}
+	c := &flightCall{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				c.err = flightPanicError(key, r)
+				LogError("Cache", "Background refresh of %s panicked: %v", key, r)
+			}
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+			c.wg.Done()
+		}()
+		if c.val, c.err = fn(); c.err != nil {
+			LogWarn("Cache", "Background refresh of %s failed: %v", key, c.err)
+		}
+	}()
+}
+
+// flightPanicError 将 fn 的 panic 转换为返回给等待者的错误
+func flightPanicError(key string, r interface{}) error {
+	return fmt.Errorf("singleflight: load of %s panicked: %v", key, r)
+}
@@ -0,0 +1,102 @@
+// storage.go
+// 存储抽象
+// 定义统一的存储后端接口，并按 StorageConfig.Type 注册/获取具体实现
+package utils
+
+import (
+	"bookweb/config"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrStorageNotExist 文件在存储中不存在
+var ErrStorageNotExist = errors.New("storage: file does not exist")
+
+// StorageFileInfo 存储文件信息
+type StorageFileInfo struct {
+	Path    string    // 相对路径 (统一使用 / 分隔)
+	Size    int64     // 文件大小 (字节)
+	ModTime time.Time // 最后修改时间
+}
+
+// Storage 存储后端接口
+// 所有路径均为相对存储根目录的路径，如 article/txt/0/1/1.txt
+type Storage interface {
+	// Read 读取文件全部内容
+	Read(relPath string) ([]byte, error)
+	// Write 写入文件 (覆盖)
+	Write(relPath string, data []byte) error
+	// Stat 获取文件信息，文件不存在时返回 ErrStorageNotExist
+	Stat(relPath string) (*StorageFileInfo, error)
+	// Delete 删除文件
+	Delete(relPath string) error
+	// List 列出指定前缀下的所有文件 (递归)
+	List(prefix string) ([]*StorageFileInfo, error)
+	// PublicURL 返回可直接对外访问的 URL，不支持时返回空字符串
+	PublicURL(relPath string) string
+}
+
+// StorageFactory 根据配置创建存储后端
+type StorageFactory func(cfg *config.StorageConfig) (Storage, error)
+
+var (
+	storageFactories = make(map[string]StorageFactory)
+	storageMu        sync.RWMutex
+
+	// 当前存储实例及其对应的配置快照，配置变化时重建
+	currentStorage    Storage
+	currentStorageCfg config.StorageConfig
+)
+
+// RegisterStorage 注册存储后端 (名称对应 StorageConfig.Type)
+func RegisterStorage(name string, factory StorageFactory) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+	storageFactories[name] = factory
+}
+
+// NewStorage 根据存储配置创建存储后端
+func NewStorage(cfg *config.StorageConfig) (Storage, error) {
+	storageType := cfg.Type
+	if storageType == "" {
+		storageType = "local"
+	}
+
+	storageMu.RLock()
+	factory, ok := storageFactories[storageType]
+	storageMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported storage type: %s", storageType)
+	}
+	return factory(cfg)
+}
+
+// GetStorage 获取当前配置对应的存储后端
+// 配置未加载时默认使用本地 files 目录
+func GetStorage() (Storage, error) {
+	var cfg config.StorageConfig
+	if appCfg := config.GetGlobalConfig(); appCfg != nil {
+		cfg = appCfg.Storage
+	}
+
+	storageMu.RLock()
+	if currentStorage != nil && currentStorageCfg == cfg {
+		s := currentStorage
+		storageMu.RUnlock()
+		return s, nil
+	}
+	storageMu.RUnlock()
+
+	s, err := NewStorage(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	storageMu.Lock()
+	currentStorage = s
+	currentStorageCfg = cfg
+	storageMu.Unlock()
+	return s, nil
+}
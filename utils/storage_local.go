@@ -0,0 +1,136 @@
+// storage_local.go
+// 本地存储
+// 基于本地磁盘 (或挂载的 NFS 目录) 的存储后端实现
+package utils
+
+import (
+	"bookweb/config"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	RegisterStorage("local", func(cfg *config.StorageConfig) (Storage, error) {
+		return NewLocalStorage(cfg.Local.Path), nil
+	})
+}
+
+// LocalStorage 本地磁盘存储
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage 创建以 root 为根目录的本地存储，root 为空时使用 files
+func NewLocalStorage(root string) *LocalStorage {
+	if root == "" {
+		root = "files"
+	}
+	return &LocalStorage{root: root}
+}
+
+// Root 返回存储根目录
+func (s *LocalStorage) Root() string {
+	return s.root
+}
+
+// FullPath 返回相对路径对应的物理路径 (已防止路径穿越)
+func (s *LocalStorage) FullPath(relPath string) string {
+	clean := path.Clean("/" + filepath.ToSlash(relPath))
+	return filepath.Join(s.root, filepath.FromSlash(clean))
+}
+
+// Read 读取文件
+func (s *LocalStorage) Read(relPath string) ([]byte, error) {
+	data, err := os.ReadFile(s.FullPath(relPath))
+	if os.IsNotExist(err) {
+		return nil, ErrStorageNotExist
+	}
+	return data, err
+}
+
+// Write 写入文件，自动创建父目录
+func (s *LocalStorage) Write(relPath string, data []byte) error {
+	fullPath := s.FullPath(relPath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免读到写了一半的文件；临时文件名唯一，并发写同一文件时互不覆盖
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), "."+filepath.Base(fullPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fullPath)
+}
+
+// Stat 获取文件信息
+func (s *LocalStorage) Stat(relPath string) (*StorageFileInfo, error) {
+	info, err := os.Stat(s.FullPath(relPath))
+	if os.IsNotExist(err) {
+		return nil, ErrStorageNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &StorageFileInfo{
+		Path:    strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(relPath)), "/"),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}, nil
+}
+
+// Delete 删除文件
+func (s *LocalStorage) Delete(relPath string) error {
+	err := os.Remove(s.FullPath(relPath))
+	if os.IsNotExist(err) {
+		return ErrStorageNotExist
+	}
+	return err
+}
+
+// List 递归列出前缀目录下的所有文件
+func (s *LocalStorage) List(prefix string) ([]*StorageFileInfo, error) {
+	base := s.FullPath(prefix)
+	var files []*StorageFileInfo
+	err := filepath.Walk(base, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, &StorageFileInfo{
+			Path:    filepath.ToSlash(rel),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	return files, err
+}
+
+// PublicURL 本地存储不提供直链，由站内路由 (如 /img/) 代理访问
+func (s *LocalStorage) PublicURL(relPath string) string {
+	return ""
+}
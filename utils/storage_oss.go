@@ -0,0 +1,249 @@
+// storage_oss.go
+// 对象存储
+// 基于 S3 兼容 HTTP 接口的对象存储后端实现 (阿里云 OSS / 腾讯云 COS / AWS S3 / MinIO 等)
+package utils
+
+import (
+	"bookweb/config"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterStorage("oss", func(cfg *config.StorageConfig) (Storage, error) {
+		return NewOssStorage(&cfg.Oss), nil
+	})
+}
+
+// OssStorage S3 兼容对象存储
+type OssStorage struct {
+	cfg    config.OssConfig
+	client *http.Client
+}
+
+// NewOssStorage 创建对象存储后端
+func NewOssStorage(cfg *config.OssConfig) *OssStorage {
+	return &OssStorage{
+		cfg:    *cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// endpoint 解析 endpoint 中的协议与主机，未指定协议时默认 https
+func (s *OssStorage) endpoint() (scheme, host string) {
+	ep := strings.TrimSpace(s.cfg.Endpoint)
+	scheme = "https"
+	if i := strings.Index(ep, "://"); i >= 0 {
+		scheme = strings.ToLower(ep[:i])
+		ep = ep[i+3:]
+	}
+	return scheme, strings.TrimRight(ep, "/")
+}
+
+// objectURL 返回对象地址
+// 虚拟主机模式: {scheme}://{bucket}.{endpoint}/{key}
+// 路径模式:     {scheme}://{endpoint}/{bucket}/{key}
+func (s *OssStorage) objectURL(relPath string, query url.Values) *url.URL {
+	key := strings.TrimLeft(relPath, "/")
+	scheme, host := s.endpoint()
+
+	p := "/" + key
+	if s.cfg.PathStyle {
+		p = "/" + s.cfg.Bucket
+		if key != "" {
+			p += "/" + key
+		}
+	} else {
+		host = s.cfg.Bucket + "." + host
+	}
+
+	u := &url.URL{Scheme: scheme, Host: host, Path: p, RawPath: s3Escape(p, false)}
+	if query != nil {
+		u.RawQuery = canonicalQueryV4(query)
+	}
+	return u
+}
+
+// region 返回签名区域
+func (s *OssStorage) region() string {
+	if s.cfg.Region != "" {
+		return s.cfg.Region
+	}
+	return "us-east-1"
+}
+
+// newRequest 构造对 bucket 的请求，配置了 AccessKey 时自动进行 SigV4 签名
+func (s *OssStorage) newRequest(method string, u *url.URL, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = int64(len(body))
+	}
+	if s.cfg.AccessKey != "" {
+		signRequestV4(req, s.cfg.AccessKey, s.cfg.SecretKey, s.region(), sha256Hex(body), time.Now())
+	}
+	return req, nil
+}
+
+// do 发送请求，非 2xx 状态码转换为错误
+func (s *OssStorage) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrStorageNotExist
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("oss storage %s failed, status: %d", req.Method, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// Read 读取对象
+// 配置了 AccessKey 时始终走签名接口 (私有 bucket)；
+// 否则如果设置了自定义域名，优先通过域名读取 (适用于公共读 / CDN 回源)
+func (s *OssStorage) Read(relPath string) ([]byte, error) {
+	var req *http.Request
+	var err error
+	if publicURL := s.PublicURL(relPath); s.cfg.AccessKey == "" && publicURL != "" {
+		req, err = http.NewRequest(http.MethodGet, publicURL, nil)
+	} else {
+		req, err = s.newRequest(http.MethodGet, s.objectURL(relPath, nil), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Write 上传对象
+func (s *OssStorage) Write(relPath string, data []byte) error {
+	req, err := s.newRequest(http.MethodPut, s.objectURL(relPath, nil), data)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Stat 获取对象信息 (HEAD)
+func (s *OssStorage) Stat(relPath string) (*StorageFileInfo, error) {
+	req, err := s.newRequest(http.MethodHead, s.objectURL(relPath, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	info := &StorageFileInfo{Path: strings.TrimLeft(relPath, "/")}
+	info.Size, _ = strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = t
+	}
+	return info, nil
+}
+
+// Delete 删除对象
+func (s *OssStorage) Delete(relPath string) error {
+	req, err := s.newRequest(http.MethodDelete, s.objectURL(relPath, nil), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listBucketResult ListObjectsV2 响应结构
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string `xml:"Key"`
+		Size         int64  `xml:"Size"`
+		LastModified string `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// List 列出前缀下的所有对象 (ListObjectsV2，自动翻页)
+func (s *OssStorage) List(prefix string) ([]*StorageFileInfo, error) {
+	var files []*StorageFileInfo
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", strings.TrimLeft(prefix, "/"))
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		req, err := s.newRequest(http.MethodGet, s.objectURL("", query), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range result.Contents {
+			info := &StorageFileInfo{Path: c.Key, Size: c.Size}
+			if t, err := time.Parse(time.RFC3339, c.LastModified); err == nil {
+				info.ModTime = t
+			}
+			files = append(files, info)
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	return files, nil
+}
+
+// PublicURL 配置了自定义域名时返回域名直链
+func (s *OssStorage) PublicURL(relPath string) string {
+	if s.cfg.Domain == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimRight(s.cfg.Domain, "/"), strings.TrimLeft(relPath, "/"))
+}
@@ -5,19 +5,31 @@ package utils
 
 import (
 	"bookweb/config"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"html/template"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 )
 
 var (
 	templateCache    = make(map[string]*template.Template)
 	templateMu       sync.RWMutex
+	templateVersion  atomic.Value // 已加载模板文件的摘要
 	GetAdContentFunc func(slotID string) template.HTML
 )
 
+// TemplateVersion 已加载模板文件 (路径及内容) 的摘要，模板未加载时返回 "0"
+func TemplateVersion() string {
+	if v, ok := templateVersion.Load().(string); ok {
+		return v
+	}
+	return "0"
+}
+
 // InitTemplates 初始化所有模板（启动时调用）
 func InitTemplates() error {
 	tpl := config.GlobalConfig.Site.Template
@@ -47,6 +59,14 @@ func InitTemplates() error {
 
 	// 清空旧缓存（避免 Reload 时残留）
 	templateCache = make(map[string]*template.Template)
+	digest := sha1.New()
+	hashFiles := func(files []string) {
+		for _, f := range files {
+			data, _ := os.ReadFile(f)
+			fmt.Fprintf(digest, "%s\x00%d\x00", f, len(data))
+			digest.Write(data)
+		}
+	}
 
 	for _, t := range templates {
 		// 1. 加载 PC 模板
@@ -71,6 +91,7 @@ func InitTemplates() error {
 				return fmt.Errorf("error parsing PC template %s: %v", t.name, err)
 			}
 			templateCache[t.name] = tmpl
+			hashFiles(files)
 			LogDebug("Template", "PC Template cached: %s", t.name)
 		}
 
@@ -99,12 +120,15 @@ func InitTemplates() error {
 					LogWarn("Template", "Error parsing Mobile template %s: %v", t.name, err)
 				} else {
 					templateCache["mobile/"+t.name] = mTmpl
+					hashFiles(mFiles)
 					LogDebug("Template", "Mobile Template cached: mobile/%s (files: %v)", t.name, mFiles)
 				}
 			}
 		}
 	}
 
+	// 模板变化后页面内容随之变化，ETag 及静态页版本依赖该摘要
+	templateVersion.Store(hex.EncodeToString(digest.Sum(nil))[:8])
 	return nil
 }
 
@@ -6,6 +6,7 @@ package utils
 import (
 	"fmt"
 	"html/template"
+	"strings"
 	"time"
 )
 
@@ -52,6 +53,15 @@ var CommonFuncMap = template.FuncMap{
 	"bookIndexPageUrl": func(id, page int) string {
 		return BookIndexPageUrl(id, page)
 	},
+	"downloadUrl": func(id int) string {
+		return BookDownloadUrl(id)
+	},
+	"epubUrl": func(id int) string {
+		return BookEpubUrl(id)
+	},
+	"voteUrl": func() string {
+		return BookVoteUrl()
+	},
 	"readUrl": func(aid, cid int) string {
 		return ReadUrl(aid, cid)
 	},
@@ -85,4 +95,16 @@ var CommonFuncMap = template.FuncMap{
 	"sortUrl": func(sortID, page int) string {
 		return SortUrl(sortID, page)
 	},
+	"formatBytes": func(n int64) string {
+		switch {
+		case n >= 1<<30:
+			return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+		case n >= 1<<20:
+			return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+		case n >= 1<<10:
+			return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+		}
+		return fmt.Sprintf("%d B", n)
+	},
+	"join": strings.Join,
 }
@@ -0,0 +1,127 @@
+// text_filter.go
+// 章节内容过滤
+// 按配置的规则链 (正则替换 / 文本替换 / 删除匹配行) 清洗章节文本中的水印、广告及敏感词
+package utils
+
+import (
+	"bookweb/config"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// 过滤规则类型
+const (
+	FilterTypeRegex   = "regex"
+	FilterTypeReplace = "replace"
+	FilterTypeDrop    = "drop"
+)
+
+// TextFilter 已编译的过滤规则链
+type TextFilter struct {
+	steps   []filterStep
+	version string // 生效规则的摘要，规则变化时随之变化
+}
+
+type filterStep struct {
+	rule config.FilterRule
+	re   *regexp.Regexp // regex / drop 规则使用
+}
+
+var currentTextFilter atomic.Pointer[TextFilter]
+
+// CompileTextFilter 编译过滤规则链，跳过未启用的规则
+// 正则表达式非法时返回错误并指出规则序号
+func CompileTextFilter(rules []config.FilterRule) (*TextFilter, error) {
+	f := &TextFilter{}
+	for i, rule := range rules {
+		if !rule.Enabled || rule.Pattern == "" {
+			continue
+		}
+		step := filterStep{rule: rule}
+		switch rule.Type {
+		case FilterTypeRegex, FilterTypeDrop:
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 条规则正则错误: %v", i+1, err)
+			}
+			step.re = re
+		case FilterTypeReplace:
+		default:
+			return nil, fmt.Errorf("第 %d 条规则类型无效: %s", i+1, rule.Type)
+		}
+		f.steps = append(f.steps, step)
+	}
+
+	h := sha1.New()
+	for _, step := range f.steps {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", step.rule.Type, step.rule.Pattern, step.rule.Replacement)
+	}
+	f.version = hex.EncodeToString(h.Sum(nil))[:8]
+	return f, nil
+}
+
+// Apply 依次执行过滤规则
+func (f *TextFilter) Apply(text string) string {
+	if f == nil {
+		return text
+	}
+	for _, step := range f.steps {
+		switch step.rule.Type {
+		case FilterTypeRegex:
+			text = step.re.ReplaceAllString(text, step.rule.Replacement)
+		case FilterTypeReplace:
+			text = strings.ReplaceAll(text, step.rule.Pattern, step.rule.Replacement)
+		case FilterTypeDrop:
+			lines := strings.Split(text, "\n")
+			kept := lines[:0]
+			for _, line := range lines {
+				if !step.re.MatchString(line) {
+					kept = append(kept, line)
+				}
+			}
+			text = strings.Join(kept, "\n")
+		}
+	}
+	return text
+}
+
+// ReloadTextFilter 根据当前配置重新编译过滤规则，并清空已渲染的章节内容缓存
+// 规则版本变化时在后台清理阅读页整页缓存 (阅读页缓存键含规则版本，清理仅为及早释放旧版本占用的内存)
+func ReloadTextFilter() error {
+	var rules []config.FilterRule
+	if cfg := config.GetGlobalConfig(); cfg != nil {
+		rules = cfg.FilterRules
+	}
+	f, err := CompileTextFilter(rules)
+	if err != nil {
+		return err
+	}
+	old := currentTextFilter.Swap(f)
+	ClearContentCache()
+
+	if old != nil && old.version != f.version {
+		go func() {
+			if _, err := ClearPageCache("read"); err != nil {
+				LogWarn("Filter", "Clear read page cache failed: %v", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// TextFilterVersion 当前生效规则的版本摘要，用于使依赖过滤结果的缓存 (EPUB、静态页等) 随规则变化失效
+func TextFilterVersion() string {
+	if f := currentTextFilter.Load(); f != nil {
+		return f.version
+	}
+	return "0"
+}
+
+// ApplyTextFilter 使用当前生效的规则过滤文本
+func ApplyTextFilter(text string) string {
+	return currentTextFilter.Load().Apply(text)
+}
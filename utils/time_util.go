@@ -4,33 +4,60 @@
 package utils
 
 import (
+	"sync/atomic"
 	"time"
+	_ "time/tzdata" // 系统未安装时区数据时仍可加载站点时区
 )
 
+// siteLocation 站点时区，未设置时使用系统时区
+var siteLocation atomic.Pointer[time.Location]
+
+// SetSiteTimezone sets the site timezone by IANA name, empty name means system local time
+func SetSiteTimezone(name string) error {
+	loc := time.Local
+	if name != "" {
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			return err
+		}
+	}
+	siteLocation.Store(loc)
+	return nil
+}
+
+// SiteLocation returns the site timezone
+func SiteLocation() *time.Location {
+	if loc := siteLocation.Load(); loc != nil {
+		return loc
+	}
+	return time.Local
+}
+
+// SiteNow returns current time in the site timezone
+func SiteNow() time.Time {
+	return time.Now().In(SiteLocation())
+}
+
 // NowTime returns current unix timestamp
 func NowTime() int64 {
 	return time.Now().Unix()
 }
 
-// IsSameDay checks if two timestamps constitute the same day
-func IsSameDay(t1, t2 int64) bool {
-	tm1 := time.Unix(t1, 0)
-	tm2 := time.Unix(t2, 0)
-	return tm1.Year() == tm2.Year() && tm1.YearDay() == tm2.YearDay()
+// DayStart returns the unix timestamp of local midnight of t's day
+func DayStart(t time.Time) int64 {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location()).Unix()
 }
 
-// IsSameWeek checks if two timestamps constitute the same week (ISO week)
-func IsSameWeek(t1, t2 int64) bool {
-	tm1 := time.Unix(t1, 0)
-	tm2 := time.Unix(t2, 0)
-	y1, w1 := tm1.ISOWeek()
-	y2, w2 := tm2.ISOWeek()
-	return y1 == y2 && w1 == w2
+// WeekStart returns the unix timestamp of local midnight of t's ISO week (Monday)
+func WeekStart(t time.Time) int64 {
+	y, m, d := t.Date()
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location()).Unix()
 }
 
-// IsSameMonth checks if two timestamps constitute the same month
-func IsSameMonth(t1, t2 int64) bool {
-	tm1 := time.Unix(t1, 0)
-	tm2 := time.Unix(t2, 0)
-	return tm1.Year() == tm2.Year() && tm1.Month() == tm2.Month()
+// MonthStart returns the unix timestamp of local midnight of the first day of t's month
+func MonthStart(t time.Time) int64 {
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location()).Unix()
 }
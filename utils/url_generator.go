@@ -125,3 +125,40 @@ func SortUrl(sortID, page int) string {
 	url := strings.Replace(pattern, ":sid", sidStr, 1)
 	return strings.Replace(url, ":page", pageStr, 1)
 }
+
+// BookDownloadUrl 根据路由配置生成全本 TXT 下载 URL
+// 未开启下载或未配置 "book_download" 路由时返回空字符串
+func BookDownloadUrl(articleID int) string {
+	return downloadRouteUrl("book_download", articleID)
+}
+
+// BookEpubUrl 根据路由配置生成 EPUB 下载 URL
+// 未开启下载或未配置 "book_epub" 路由时返回空字符串
+func BookEpubUrl(articleID int) string {
+	return downloadRouteUrl("book_epub", articleID)
+}
+
+// downloadRouteUrl 生成下载类路由 URL
+func downloadRouteUrl(name string, articleID int) string {
+	appCfg := config.GetGlobalConfig()
+	cfg := config.GetRouterConfig()
+	if appCfg == nil || !appCfg.Site.DownloadEnabled || cfg == nil {
+		return ""
+	}
+	pattern := cfg.GetRoute(name)
+	if pattern == "" {
+		return ""
+	}
+	return strings.Replace(pattern, ":aid", strconv.Itoa(EncodeID(articleID)), 1)
+}
+
+// BookVoteUrl 根据路由配置生成推荐票投票地址 (POST)
+// 未开启推荐票或未配置 "book_vote" 路由时返回空字符串
+func BookVoteUrl() string {
+	appCfg := config.GetGlobalConfig()
+	cfg := config.GetRouterConfig()
+	if appCfg == nil || appCfg.Site.DailyVotes <= 0 || cfg == nil {
+		return ""
+	}
+	return cfg.GetRoute("book_vote")
+}